@@ -0,0 +1,21 @@
+package app
+
+import (
+	"snap-screen/pkg/client"
+
+	"fyne.io/fyne/v2/canvas"
+)
+
+// canvasSink 把 Viewer 收到的帧显示到 fyne 的 canvas.Image 上，是 client.FrameSink 的界面适配
+type canvasSink struct {
+	img *canvas.Image
+}
+
+var _ client.FrameSink = canvasSink{}
+
+// WriteFrame 实现 client.FrameSink
+func (c canvasSink) WriteFrame(f client.Frame) error {
+	c.img.Image = f.Image
+	c.img.Refresh()
+	return nil
+}
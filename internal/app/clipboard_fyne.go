@@ -0,0 +1,34 @@
+package app
+
+import (
+	"snap-screen/pkg/clipboard"
+
+	"fyne.io/fyne/v2"
+)
+
+// fyneClipboard 通过 fyne 的剪贴板 API 读写系统剪贴板，实现 clipboard.Clipboard。
+// fyne 的剪贴板只支持文本，写入图片时返回 clipboard.ErrImageUnsupported。
+type fyneClipboard struct {
+	cb fyne.Clipboard
+}
+
+var _ clipboard.Clipboard = (*fyneClipboard)(nil)
+
+// newFyneClipboard 包装窗口的剪贴板，通常传入 w.Clipboard()
+func newFyneClipboard(cb fyne.Clipboard) *fyneClipboard {
+	return &fyneClipboard{cb: cb}
+}
+
+// Read 读取剪贴板中的文本
+func (f *fyneClipboard) Read() (clipboard.Content, error) {
+	return clipboard.Content{Text: f.cb.Content()}, nil
+}
+
+// Write 把文本写入剪贴板
+func (f *fyneClipboard) Write(c clipboard.Content) error {
+	if c.IsImage() {
+		return clipboard.ErrImageUnsupported
+	}
+	f.cb.SetContent(c.Text)
+	return nil
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"snap-screen/pkg/clipboard"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// describeClipboard 返回用于提示的简短描述，文本只截取开头
+func describeClipboard(c clipboard.Content) string {
+	if c.IsImage() {
+		return fmt.Sprintf("图片 %d KB", (c.Size()+1023)/1024)
+	}
+	text := []rune(c.Text)
+	if len(text) > 40 {
+		return string(text[:40]) + "…"
+	}
+	return string(text)
+}
+
+// notifyClipboard 发送系统通知，每次剪贴板传输都会提示
+func notifyClipboard(title string, c clipboard.Content) {
+	fyne.CurrentApp().SendNotification(fyne.NewNotification(title, describeClipboard(c)))
+}
+
+// showClipboardImage 预览收到的图片并提供另存为，用于不支持图片的 fyne 剪贴板
+func showClipboardImage(w fyne.Window, title string, c clipboard.Content) {
+	showImage(w, title, "剪贴板不支持图片，可另存为文件", "clipboard.png", c.Image, fyne.NewSize(320, 240))
+}
+
+// showImage 在对话框中预览图片，附带说明文字和另存为按钮，name 为另存为的默认文件名
+func showImage(w fyne.Window, title, hint, name string, data []byte, minSize fyne.Size) {
+	img := canvas.NewImageFromReader(bytes.NewReader(data), name)
+	img.FillMode = canvas.ImageFillContain
+	img.SetMinSize(minSize)
+	save := widget.NewButton("另存为…", func() {
+		d := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil || wc == nil {
+				return
+			}
+			defer wc.Close()
+			if _, err := wc.Write(data); err != nil {
+				dialog.ShowError(err, w)
+			}
+		}, w)
+		d.SetFileName(name)
+		d.Show()
+	})
+	dialog.ShowCustom(title, "关闭", container.NewBorder(widget.NewLabel(hint), save, nil, nil, img), w)
+}
+
+// pickClipboardImage 选择一张 PNG / JPEG 图片作为剪贴板内容
+func pickClipboardImage(w fyne.Window, limits clipboard.Limits, onPicked func(clipboard.Content)) {
+	d := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil || rc == nil {
+			return
+		}
+		defer rc.Close()
+		// 多读 1 字节，超出上限时交给 Check 报错
+		maxImage := limits.MaxImage
+		if maxImage <= 0 {
+			maxImage = clipboard.DefaultMaxImage
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, int64(maxImage)+1))
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		c := clipboard.Content{Image: data}
+		if err := limits.Check(c); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		onPicked(c)
+	}, w)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".png", ".jpg", ".jpeg"}))
+	d.Show()
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"snap-screen/pkg/client"
+)
+
+// 命令行子命令的退出码
+const (
+	exitOK      = 0 // 正常结束：收到 SIGINT / SIGTERM，或达到指定的帧数 / 时长
+	exitFailure = 1 // 运行中异常结束，例如信令连接断开、超时未收到画面
+	exitUsage   = 2 // 参数错误
+	exitStartup = 3 // 启动失败：画面来源、信令服务器、输出目录等不可用
+)
+
+// statusPrinter 把状态逐行写到 w；状态回调可能来自多个协程，需要加锁
+type statusPrinter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	json bool
+}
+
+func newStatusPrinter(w io.Writer, jsonOutput bool) *statusPrinter {
+	return &statusPrinter{w: w, json: jsonOutput}
+}
+
+// statusLine 为 JSON 输出的一行；State 为稳定的英文状态名，便于脚本判断
+type statusLine struct {
+	Time   string `json:"time"`
+	Stream string `json:"stream"`
+	State  string `json:"state"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+var publisherStates = map[client.PublisherStatus]string{
+	client.PublisherStatusDisconnected: "disconnected",
+	client.PublisherStatusConnected:    "connected",
+	client.PublisherStatusError:        "error",
+	client.PublisherStatusRunning:      "running",
+	client.PublisherStatusPaused:       "paused",
+	client.PublisherStatusStopped:      "stopped",
+}
+
+// publisher 输出一条 Publisher 状态
+func (p *statusPrinter) publisher(streamID string, st client.PublisherStatus, detail string) {
+	p.print(streamID, publisherStates[st], string(st), detail)
+}
+
+// print 输出一行状态；state 为英文状态名，status 为中文展示文字
+func (p *statusPrinter) print(streamID, state, status, detail string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if !p.json {
+		fmt.Fprintf(p.w, "%s [%s] %s\n", now.Format("15:04:05"), status, detail)
+		return
+	}
+	b, err := json.Marshal(statusLine{
+		Time:   now.Format(time.RFC3339Nano),
+		Stream: streamID,
+		State:  state,
+		Status: status,
+		Detail: detail,
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(p.w, string(b))
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"strings"
+
+	"snap-screen/pkg/client"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// iceOptions 是 Publisher / Viewer 共用的 ICE 配置表单
+type iceOptions struct {
+	lanOnly    *widget.Check
+	servers    *widget.Entry
+	username   *widget.Entry
+	credential *widget.Entry
+	ifaces     *widget.Entry
+}
+
+func newICEOptions() *iceOptions {
+	o := &iceOptions{
+		servers:    widget.NewEntry(),
+		username:   widget.NewEntry(),
+		credential: widget.NewPasswordEntry(),
+		ifaces:     widget.NewEntry(),
+	}
+	o.servers.SetPlaceHolder("ICE 服务器，逗号分隔（留空=默认公共 STUN）")
+	o.username.SetPlaceHolder("TURN 用户名")
+	o.credential.SetPlaceHolder("TURN 密码")
+	o.ifaces.SetPlaceHolder("仅使用这些网卡，逗号分隔（留空=全部）")
+
+	o.lanOnly = widget.NewCheck("仅局域网（离线，只收集本机候选）", func(on bool) {
+		// 离线模式下服务器配置不会生效，直接禁用避免误解
+		if on {
+			o.servers.Disable()
+			o.username.Disable()
+			o.credential.Disable()
+		} else {
+			o.servers.Enable()
+			o.username.Enable()
+			o.credential.Enable()
+		}
+	})
+	return o
+}
+
+// form 返回可直接放入窗口布局的表单控件
+func (o *iceOptions) form() fyne.CanvasObject {
+	return container.NewVBox(
+		widget.NewLabel("ICE 设置"),
+		o.lanOnly,
+		o.servers,
+		container.NewGridWithColumns(2, o.username, o.credential),
+		o.ifaces,
+	)
+}
+
+// config 根据表单内容构造 client.ICEConfig
+func (o *iceOptions) config() client.ICEConfig {
+	cfg := client.ICEConfig{
+		LANOnly:    o.lanOnly.Checked,
+		Interfaces: splitList(o.ifaces.Text),
+	}
+	if cfg.LANOnly {
+		return cfg
+	}
+
+	// STUN 与 TURN 分开配置：TURN 需要凭据，STUN 不需要
+	var stun, turn []string
+	for _, u := range splitList(o.servers.Text) {
+		if strings.HasPrefix(u, "turn:") || strings.HasPrefix(u, "turns:") {
+			turn = append(turn, u)
+		} else {
+			stun = append(stun, u)
+		}
+	}
+	if len(stun) > 0 {
+		cfg.Servers = append(cfg.Servers, client.ICEServer{URLs: stun})
+	}
+	if len(turn) > 0 {
+		cfg.Servers = append(cfg.Servers, client.ICEServer{
+			URLs:       turn,
+			Username:   strings.TrimSpace(o.username.Text),
+			Credential: o.credential.Text,
+		})
+	}
+	return cfg
+}
+
+func (o *iceOptions) setEnabled(enabled bool) {
+	for _, w := range []fyne.Disableable{o.lanOnly, o.servers, o.username, o.credential, o.ifaces} {
+		if enabled {
+			w.Enable()
+		} else {
+			w.Disable()
+		}
+	}
+	if enabled && o.lanOnly.Checked {
+		o.servers.Disable()
+		o.username.Disable()
+		o.credential.Disable()
+	}
+}
+
+// splitList 按逗号拆分并去掉空白项
+func splitList(text string) []string {
+	var out []string
+	for _, part := range strings.Split(text, ",") {
+		if v := strings.TrimSpace(part); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,296 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"snap-screen/internal/server"
+	"snap-screen/pkg/client"
+	"snap-screen/pkg/codec"
+	"snap-screen/pkg/screen"
+	"snap-screen/pkg/sink"
+	"snap-screen/pkg/source"
+	"snap-screen/pkg/utils"
+)
+
+// publishOptions 为 publish 子命令的参数
+type publishOptions struct {
+	display    int
+	region     string
+	fps        int
+	size       string
+	signalURL  string
+	streamID   string
+	serve      string
+	beacon     bool
+	src        string
+	sourcePath string
+	codec      string
+	quality    int
+	record     string
+	segSize    int
+	segTime    time.Duration
+	jsonOutput bool
+
+	// rect 为 config 解析出的 -region，未指定时为 nil
+	rect *image.Rectangle
+}
+
+// RunPublishCommand 实现 publish 子命令：不打开窗口，直接开始分享，直到收到 SIGINT / SIGTERM。
+// 状态变化逐行输出到标准输出（文本或 JSON），返回进程退出码。
+func RunPublishCommand(args []string) int {
+	var opts publishOptions
+	fs := flag.NewFlagSet("publish", flag.ContinueOnError)
+	fs.IntVar(&opts.display, "display", 0, "屏幕索引（-source screen 时有效）")
+	fs.StringVar(&opts.region, "region", "", "捕获区域 x,y,width,height（相对屏幕左上角），默认整个屏幕")
+	fs.IntVar(&opts.fps, "fps", 30, "帧率（上限 30）")
+	fs.StringVar(&opts.size, "size", "", "输出分辨率 WIDTHxHEIGHT，默认保持原始分辨率")
+	fs.StringVar(&opts.signalURL, "signal", "", "信令服务器地址，默认 ws://127.0.0.1:8080/ws；与 -serve 同时使用时指向内嵌服务器")
+	fs.StringVar(&opts.streamID, "stream", "", "stream ID，默认随机生成")
+	fs.StringVar(&opts.serve, "serve", "", "在本进程内启动信令服务器并监听该地址，例如 :8080")
+	fs.BoolVar(&opts.beacon, "beacon", false, "在局域网广播内嵌信令服务器，供 Viewer 自动发现（需要 -serve）")
+	fs.StringVar(&opts.src, "source", "screen", "画面来源: screen / testpattern / slideshow / mjpeg")
+	fs.StringVar(&opts.sourcePath, "source-path", "", "slideshow 的图片文件夹或 mjpeg 的文件路径")
+	fs.StringVar(&opts.codec, "codec", "jpeg", "首选帧编码: jpeg / png / qoi")
+	fs.IntVar(&opts.quality, "quality", 0, "JPEG 质量 1~100，默认 60")
+	fs.StringVar(&opts.record, "record", "", "同时把输出画面录制为 Motion-JPEG AVI，保存到该目录")
+	fs.IntVar(&opts.segSize, "segment-size", 0, "-record 时每个文件的大小上限（MB），0 表示不限（单个文件最大 1 GB）")
+	fs.DurationVar(&opts.segTime, "segment-duration", 0, "-record 时每个文件的时长上限，例如 10m，0 表示不限")
+	fs.BoolVar(&opts.jsonOutput, "json", false, "以 JSON Lines 输出状态")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintln(out, "用法: snap-screen publish [参数]")
+		fmt.Fprintln(out, "\n不打开窗口直接分享，按 Ctrl+C 或发送 SIGTERM 停止并注销 stream。")
+		fmt.Fprintln(out, "退出码: 0 正常停止，1 运行中异常结束，2 参数错误，3 启动失败\n\n参数:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "多余的参数:", strings.Join(fs.Args(), " "))
+		return exitUsage
+	}
+
+	cfg, err := opts.config()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "参数错误:", err)
+		return exitUsage
+	}
+	out := newStatusPrinter(os.Stdout, opts.jsonOutput)
+	cfg.OnViewerRecording = func(peerID string, recording bool) {
+		if recording {
+			out.print(opts.streamID, "viewer_recording", "录制提示", "Viewer "+peerID+" 开始录制")
+		} else {
+			out.print(opts.streamID, "viewer_recording_stopped", "录制提示", "Viewer "+peerID+" 已停止录制")
+		}
+	}
+
+	if opts.serve != "" {
+		addr, stop, err := server.StartHTTPServer(opts.serve)
+		if err != nil {
+			out.publisher(opts.streamID, client.PublisherStatusError, "启动内嵌信令服务器失败: "+err.Error())
+			return exitStartup
+		}
+		defer stop()
+		_, port, _ := net.SplitHostPort(addr)
+		if cfg.SignalURL == "" {
+			cfg.SignalURL = "ws://127.0.0.1:" + port + "/ws"
+		}
+		out.publisher(opts.streamID, client.PublisherStatusDisconnected, "已启动内嵌信令服务器: "+addr)
+		if opts.beacon {
+			portInt, _ := strconv.Atoi(port)
+			defer startDiscoveryBroadcast(portInt)()
+		}
+	}
+
+	src, closeSource, err := opts.source(cfg.Width, cfg.Height)
+	if err != nil {
+		out.publisher(opts.streamID, client.PublisherStatusError, err.Error())
+		return exitStartup
+	}
+	defer closeSource()
+
+	// 在启动之前注册信号，避免启动过程中按 Ctrl+C 直接杀掉进程而没有注销 stream
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	signalURL := cfg.SignalURL
+	if signalURL == "" {
+		signalURL = "默认信令服务器"
+	}
+	out.publisher(opts.streamID, client.PublisherStatusDisconnected, "正在注册 stream "+opts.streamID+" 到 "+signalURL)
+	pub, err := client.StartPublisher(opts.streamID, src, cfg, func(st client.PublisherStatus, detail string) {
+		out.publisher(opts.streamID, st, detail)
+	})
+	if err != nil {
+		out.publisher(opts.streamID, client.PublisherStatusError, "启动推流失败: "+err.Error())
+		return exitStartup
+	}
+	if opts.record != "" {
+		rec, err := sink.NewAVI(opts.record, sink.AVIOptions{
+			Prefix:      recordFilePrefix("publish", opts.streamID),
+			MaxSize:     int64(opts.segSize) << 20,
+			MaxDuration: opts.segTime,
+			OnSegment: func(path string, err error) {
+				if err != nil {
+					out.print(opts.streamID, "error", "错误", "录制文件收尾失败: "+err.Error())
+					return
+				}
+				out.print(opts.streamID, "saved", "已保存", path)
+			},
+		})
+		if err != nil {
+			pub.Stop()
+			out.publisher(opts.streamID, client.PublisherStatusError, "创建录制目录失败: "+err.Error())
+			return exitStartup
+		}
+		pub.StartRecording(rec)
+		defer func() {
+			pub.StopRecording()
+			// 收尾失败已由 OnSegment 输出
+			_ = rec.Close()
+		}()
+	}
+
+	// 注册被信令服务器拒绝（例如 stream ID 已被占用）时会话在注册之前结束，视为启动失败
+	select {
+	case <-ctx.Done():
+		pub.Stop()
+		return exitOK
+	case <-pub.Done():
+		select {
+		case <-pub.Registered():
+		default:
+			return exitStartup
+		}
+	case <-pub.Registered():
+	}
+
+	select {
+	case <-ctx.Done():
+		// Stop 会向信令服务器发送 unregister，Viewer 随即收到 stream 已移除
+		pub.Stop()
+		return exitOK
+	case <-pub.Done():
+		if pub.Wait() != nil {
+			return exitFailure
+		}
+		return exitOK
+	}
+}
+
+// config 校验参数并生成 PublisherConfig
+func (o *publishOptions) config() (client.PublisherConfig, error) {
+	cfg := client.PublisherConfig{SignalURL: o.signalURL, FrameRate: o.fps, JPEGQuality: o.quality}
+	if o.fps <= 0 {
+		return cfg, errors.New("-fps 必须是正整数")
+	}
+	if o.quality < 0 || o.quality > 100 {
+		return cfg, errors.New("-quality 必须是 1~100 的整数")
+	}
+	if o.size != "" {
+		w, h, ok := strings.Cut(strings.ToLower(o.size), "x")
+		width, errW := strconv.Atoi(strings.TrimSpace(w))
+		height, errH := strconv.Atoi(strings.TrimSpace(h))
+		if !ok || errW != nil || errH != nil || width <= 0 || height <= 0 {
+			return cfg, errors.New("-size 格式应为 WIDTHxHEIGHT，例如 1280x720")
+		}
+		cfg.Width, cfg.Height = width, height
+	}
+	id, err := codec.ParseID(o.codec)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Codec = id
+	if o.segSize < 0 || o.segTime < 0 {
+		return cfg, errors.New("-segment-size / -segment-duration 不能为负数")
+	}
+	if o.beacon && o.serve == "" {
+		return cfg, errors.New("-beacon 需要同时指定 -serve")
+	}
+	switch o.src {
+	case "screen", "testpattern":
+	case "slideshow", "mjpeg":
+		if o.sourcePath == "" {
+			return cfg, fmt.Errorf("-source %s 需要同时指定 -source-path", o.src)
+		}
+	default:
+		return cfg, errors.New("未知的画面来源: " + o.src)
+	}
+	if o.region != "" {
+		rect, err := parseRegion(o.region)
+		if err != nil {
+			return cfg, err
+		}
+		o.rect = &rect
+	}
+	if o.streamID == "" {
+		o.streamID = utils.GenID()
+	}
+	return cfg, nil
+}
+
+// source 按 config 校验过的参数创建画面来源，返回的 close 函数总是非 nil
+func (o *publishOptions) source(width, height int) (source.FrameSource, func(), error) {
+	noop := func() {}
+	switch o.src {
+	case "testpattern":
+		return source.NewTestPattern(width, height), noop, nil
+	case "slideshow":
+		ss, err := source.NewSlideshow(o.sourcePath, 5*time.Second)
+		if err != nil {
+			return nil, noop, fmt.Errorf("打开图片文件夹失败: %w", err)
+		}
+		return ss, noop, nil
+	case "mjpeg":
+		replay, err := source.NewMJPEGReplay(o.sourcePath, o.fps, true)
+		if err != nil {
+			return nil, noop, fmt.Errorf("打开 MJPEG 文件失败: %w", err)
+		}
+		return replay, func() { replay.Close() }, nil
+	}
+
+	displays := screen.ListDisplays()
+	if o.display < 0 || o.display >= len(displays) {
+		return nil, noop, fmt.Errorf("屏幕索引 %d 无效，当前共有 %d 块屏幕", o.display, len(displays))
+	}
+	capture := screen.NewCapture(o.display)
+	if o.rect != nil {
+		capture.SetRegion(o.rect)
+	}
+	return capture, noop, nil
+}
+
+// parseRegion 解析 "x,y,width,height"
+func parseRegion(text string) (image.Rectangle, error) {
+	parts := strings.Split(text, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, errors.New("-region 格式应为 x,y,width,height")
+	}
+	var v [4]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return image.Rectangle{}, errors.New("-region 格式应为 x,y,width,height")
+		}
+		v[i] = n
+	}
+	if v[2] <= 0 || v[3] <= 0 {
+		return image.Rectangle{}, errors.New("-region 的 width / height 必须大于 0")
+	}
+	return image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]), nil
+}
@@ -1,19 +1,31 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"log"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"snap-screen/internal/server"
 	"snap-screen/pkg/client"
+	"snap-screen/pkg/clipboard"
+	"snap-screen/pkg/codec"
+	"snap-screen/pkg/input"
+	"snap-screen/pkg/overlay"
 	"snap-screen/pkg/screen"
+	"snap-screen/pkg/source"
+	"snap-screen/pkg/transfer"
 	"snap-screen/pkg/utils"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -24,6 +36,14 @@ var (
 	embeddedDiscoverStop func()
 )
 
+// 画面来源选项
+const (
+	sourceScreen      = "屏幕"
+	sourceTestPattern = "测试图案"
+	sourceSlideshow   = "图片文件夹"
+	sourceMJPEG       = "MJPEG 文件回放"
+)
+
 // RunPublisherUI Publisher GUI
 func RunPublisherUI(a fyne.App, w fyne.Window) {
 	w.SetTitle("Publisher - 分享屏幕")
@@ -51,11 +71,28 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 	})
 	screenSelect.SetSelected(screenOptions[0])
 
+	// 画面来源：默认是屏幕，也可以使用测试图案、图片文件夹或录制好的 MJPEG 文件
+	sourceSelect := widget.NewSelect([]string{sourceScreen, sourceTestPattern, sourceSlideshow, sourceMJPEG}, nil)
+	sourceSelect.SetSelected(sourceScreen)
+	sourcePathEntry := widget.NewEntry()
+	sourcePathEntry.SetPlaceHolder("图片文件夹或 MJPEG 文件路径")
+
 	fpsEntry := widget.NewEntry()
 	// 默认使用 30fps，避免在高分辨率屏幕上 CPU 占用过高导致卡顿
 	fpsEntry.SetText("30")
 	fpsEntry.SetPlaceHolder("帧率 (默认 30，建议 20~30)")
 
+	// JPEG 体积小但小字号代码会糊；无损编码适合分享代码、文档等以文字为主的画面
+	codecOptions := map[string]codec.ID{
+		"JPEG（默认）":   codec.JPEG,
+		"清晰文本 · QOI": codec.QOI,
+		"清晰文本 · PNG": codec.PNG,
+	}
+	codecSelect := widget.NewSelect([]string{"JPEG（默认）", "清晰文本 · QOI", "清晰文本 · PNG"}, nil)
+	codecSelect.SetSelected("JPEG（默认）")
+	qualityEntry := widget.NewEntry()
+	qualityEntry.SetPlaceHolder("JPEG 质量 1~100（默认 60）")
+
 	widthEntry := widget.NewEntry()
 	widthEntry.SetPlaceHolder("输出宽度（留空=原始）")
 	heightEntry := widget.NewEntry()
@@ -71,10 +108,82 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 	rwEntry.SetPlaceHolder("width")
 	rhEntry.SetPlaceHolder("height")
 
+	// 遮挡区域在分享过程中也可以修改，点击"应用遮挡"后下一帧生效
+	maskEntry := widget.NewMultiLineEntry()
+	maskEntry.SetPlaceHolder("遮挡区域，每行一个: x,y,width,height（相对捕获区域）")
+	maskPercent := widget.NewCheck("坐标按百分比 (0~100)", nil)
+	maskStyles := map[string]screen.MaskStyle{
+		"纯黑":  screen.MaskBlack,
+		"马赛克": screen.MaskPixelate,
+		"模糊":  screen.MaskBlur,
+	}
+	maskStyleSelect := widget.NewSelect([]string{"纯黑", "马赛克", "模糊"}, nil)
+	maskStyleSelect.SetSelected("纯黑")
+	currentMasks := func() ([]screen.Mask, error) {
+		return parseMasks(maskEntry.Text, maskPercent.Checked, maskStyles[maskStyleSelect.Selected])
+	}
+
+	// 水印：在缩放后、编码前叠加到每一帧
+	watermarkEntry := widget.NewEntry()
+	watermarkEntry.SetPlaceHolder("水印文字，支持 {name} {time}（留空=不显示）")
+	watermarkAnchors := map[string]overlay.Anchor{
+		"右下": overlay.BottomRight,
+		"左下": overlay.BottomLeft,
+		"右上": overlay.TopRight,
+		"左上": overlay.TopLeft,
+		"居中": overlay.Center,
+	}
+	watermarkAnchorSelect := widget.NewSelect([]string{"右下", "左下", "右上", "左上", "居中"}, nil)
+	watermarkAnchorSelect.SetSelected("右下")
+	confidentialCheck := widget.NewCheck("顶部 CONFIDENTIAL 横幅", nil)
+	logoEntry := widget.NewEntry()
+	logoEntry.SetPlaceHolder("Logo 图片路径（PNG/JPEG，可选，显示在左上角）")
+
+	iceOpts := newICEOptions()
+
+	// 远程控制需要显式开启；开启后 Viewer 的每次申请仍需逐一确认
+	remoteCheck := widget.NewCheck("允许 Viewer 申请远程控制（仅屏幕来源）", nil)
+
+	// 默认各 Viewer 自行绘制标注；烧录后录屏和所有观看端看到的画面完全一致
+	burnCheck := widget.NewCheck("把 Viewer 的标注烧录进画面", nil)
+
+	// 剪贴板共享同样需要显式开启，回传还需单独允许
+	clipboardCheck := widget.NewCheck("开启剪贴板共享", nil)
+	clipboardUploadCheck := widget.NewCheck("允许 Viewer 回传剪贴板", nil)
+
+	// 原始分辨率截图默认允许，按 Viewer 限频
+	snapshotCheck := widget.NewCheck("允许 Viewer 请求原始分辨率截图", nil)
+	snapshotCheck.SetChecked(true)
+	snapshotIntervals := map[string]time.Duration{
+		"每 5 秒最多一张":  5 * time.Second,
+		"每 30 秒最多一张": 30 * time.Second,
+		"不限频率":       -1,
+	}
+	snapshotIntervalSelect := widget.NewSelect([]string{"每 5 秒最多一张", "每 30 秒最多一张", "不限频率"}, nil)
+	snapshotIntervalSelect.SetSelected("每 5 秒最多一张")
+
+	// 暂停分享时 Viewer 看到的占位画面
+	placeholderModes := map[string]client.PlaceholderMode{
+		"提示画面":   client.PlaceholderSlate,
+		"模糊最后一帧": client.PlaceholderBlur,
+	}
+	placeholderSelect := widget.NewSelect([]string{"提示画面", "模糊最后一帧"}, nil)
+	placeholderSelect.SetSelected("提示画面")
+
 	statusLabel := widget.NewLabel("状态: 未连接")
 	statusDetail := widget.NewLabel("")
+	statsLabel := widget.NewLabel("")
+	statsLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	transfers := newTransferPanel()
 
 	var running bool
+	// 当前窗口对应的推流会话，多个窗口/多块屏幕可以各自持有独立会话
+	var pub *client.Publisher
+	var capture *screen.Capture
+	// 非屏幕来源（如 MJPEG 回放）持有文件句柄，停止分享时需要关闭
+	var closeSource func()
+	// 远程控制的输入注入器，停止分享时关闭
+	var injector input.InputInjector
 	// 确保仅在本窗口生命周期内启动一次内嵌服务器
 	startEmbeddedIfNeeded := func() error {
 		if embeddedSignalStop != nil {
@@ -112,6 +221,17 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 
 	var startBtn *widget.Button
 	var stopBtn *widget.Button
+	var pauseBtn *widget.Button
+	var revokeBtn *widget.Button
+	var clearAnnotationsBtn *widget.Button
+	var pushClipboardBtn, pushImageBtn *widget.Button
+
+	// 录制本机的输出画面（含水印和烧录的标注），没有 Viewer 时也可以录制
+	recordCtl := newRecordControl("publish",
+		func(rec client.FrameSink) { pub.StartRecording(rec) },
+		func() client.FrameSink { return pub.StopRecording() },
+		statusDetail.SetText)
+	recordCtl.button.Disable()
 	startBtn = widget.NewButton("开始分享", func() {
 		if running {
 			return
@@ -122,23 +242,12 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 			return
 		}
 
-		if len(displays) == 0 {
-			statusLabel.SetText("状态: 错误")
-			statusDetail.SetText("未找到可用屏幕")
-			return
-		}
-
 		streamID := strings.TrimSpace(streamIDEntry.Text)
 		if streamID == "" {
 			streamID = utils.GenID()
 			streamIDEntry.SetText(streamID)
 		}
 
-		screenInfo, ok := labelToDisplay[screenSelect.Selected]
-		if !ok {
-			screenInfo = displays[0]
-		}
-
 		fps, err := parseIntOrDefault(fpsEntry.Text, 60)
 		if err != nil || fps <= 0 {
 			statusLabel.SetText("状态: 错误")
@@ -158,19 +267,85 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 			return
 		}
 
-		capture := screen.NewCapture(screenInfo.Index)
-		if regionCheck.Checked {
-			x, errX := parseIntOrDefault(xEntry.Text, 0)
-			y, errY := parseIntOrDefault(yEntry.Text, 0)
-			rw, errW := parseIntOrDefault(rwEntry.Text, 0)
-			rh, errH := parseIntOrDefault(rhEntry.Text, 0)
-			if errX != nil || errY != nil || errW != nil || errH != nil || rw <= 0 || rh <= 0 {
+		quality, err := parseIntOrDefault(qualityEntry.Text, 0)
+		if err != nil || quality < 0 || quality > 100 {
+			statusLabel.SetText("状态: 错误")
+			statusDetail.SetText("JPEG 质量必须是 1~100 的整数")
+			return
+		}
+
+		var src source.FrameSource
+		sourcePath := strings.TrimSpace(sourcePathEntry.Text)
+		switch sourceSelect.Selected {
+		case sourceTestPattern:
+			src = source.NewTestPattern(width, height)
+		case sourceSlideshow:
+			ss, err := source.NewSlideshow(sourcePath, 5*time.Second)
+			if err != nil {
+				statusLabel.SetText("状态: 错误")
+				statusDetail.SetText("打开图片文件夹失败: " + err.Error())
+				return
+			}
+			src = ss
+		case sourceMJPEG:
+			replay, err := source.NewMJPEGReplay(sourcePath, fps, true)
+			if err != nil {
+				statusLabel.SetText("状态: 错误")
+				statusDetail.SetText("打开 MJPEG 文件失败: " + err.Error())
+				return
+			}
+			src = replay
+			closeSource = func() { replay.Close() }
+		default:
+			if len(displays) == 0 {
+				statusLabel.SetText("状态: 错误")
+				statusDetail.SetText("未找到可用屏幕")
+				return
+			}
+			screenInfo, ok := labelToDisplay[screenSelect.Selected]
+			if !ok {
+				screenInfo = displays[0]
+			}
+			masks, err := currentMasks()
+			if err != nil {
+				statusLabel.SetText("状态: 错误")
+				statusDetail.SetText(err.Error())
+				return
+			}
+			capture = screen.NewCapture(screenInfo.Index)
+			capture.SetMasks(masks)
+			if regionCheck.Checked {
+				x, errX := parseIntOrDefault(xEntry.Text, 0)
+				y, errY := parseIntOrDefault(yEntry.Text, 0)
+				rw, errW := parseIntOrDefault(rwEntry.Text, 0)
+				rh, errH := parseIntOrDefault(rhEntry.Text, 0)
+				if errX != nil || errY != nil || errW != nil || errH != nil || rw <= 0 || rh <= 0 {
+					statusLabel.SetText("状态: 错误")
+					statusDetail.SetText("区域参数无效，需填写 x/y/width/height，且 width/height > 0")
+					return
+				}
+				rect := image.Rect(x, y, x+rw, y+rh)
+				capture.SetRegion(&rect)
+			}
+			src = capture
+		}
+
+		overlayCfg, err := buildOverlayConfig(watermarkEntry.Text, watermarkAnchors[watermarkAnchorSelect.Selected],
+			confidentialCheck.Checked, strings.TrimSpace(logoEntry.Text))
+		if err != nil {
+			statusLabel.SetText("状态: 错误")
+			statusDetail.SetText(err.Error())
+			return
+		}
+
+		if remoteCheck.Checked && capture != nil {
+			x, err := input.NewXTestInjector()
+			if err != nil {
 				statusLabel.SetText("状态: 错误")
-				statusDetail.SetText("区域参数无效，需填写 x/y/width/height，且 width/height > 0")
+				statusDetail.SetText("初始化远程控制失败: " + err.Error())
 				return
 			}
-			rect := image.Rect(x, y, x+rw, y+rh)
-			capture.SetRegion(&rect)
+			injector = x
 		}
 
 		cfg := client.PublisherConfig{
@@ -178,28 +353,117 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 			FrameRate: fps,
 			Width:     width,
 			Height:    height,
+			ICE:       iceOpts.config(),
+			Placeholder: client.PlaceholderConfig{
+				Mode: placeholderModes[placeholderSelect.Selected],
+			},
+			Overlay:               overlayCfg,
+			Codec:                 codecOptions[codecSelect.Selected],
+			JPEGQuality:           quality,
+			Input:                 injector,
+			BurnAnnotations:       burnCheck.Checked,
+			AcceptViewerClipboard: clipboardUploadCheck.Checked,
+			DisableSnapshots:      !snapshotCheck.Checked,
+			SnapshotInterval:      snapshotIntervals[snapshotIntervalSelect.Selected],
+			OnFileOffer: func(peerID string, o transfer.Offer) {
+				askAcceptFile(w, "Viewer "+peerID, o,
+					func(dir string) error { return pub.AcceptFile(peerID, o.ID, dir) },
+					func() error { return pub.RejectFile(peerID, o.ID) })
+			},
+			OnTransfer: func(peerID string, p transfer.Progress) {
+				transfers.update("Viewer "+peerID, p)
+			},
+			OnClipboard: func(peerID string, c clipboard.Content, err error) {
+				switch {
+				case errors.Is(err, clipboard.ErrImageUnsupported):
+					notifyClipboard("收到 Viewer "+peerID+" 的图片", c)
+					showClipboardImage(w, "来自 "+peerID+" 的图片", c)
+				case err != nil:
+					statusDetail.SetText("拒绝来自 " + peerID + " 的剪贴板: " + err.Error())
+				default:
+					notifyClipboard("剪贴板已更新（来自 Viewer "+peerID+"）", c)
+					statusDetail.SetText("已写入来自 " + peerID + " 的剪贴板")
+				}
+			},
+			OnViewerRecording: func(peerID string, recording bool) {
+				title := "Viewer " + peerID + " 开始录制"
+				if !recording {
+					title = "Viewer " + peerID + " 已停止录制"
+				}
+				fyne.CurrentApp().SendNotification(fyne.NewNotification(title, "stream "+streamID))
+				statusDetail.SetText(title)
+			},
+			OnControlRequest: func(peerID string) {
+				dialog.ShowConfirm("远程控制申请",
+					"Viewer "+peerID+" 申请控制本机的键盘和鼠标，是否允许？\n可随时点击「收回控制」结束。",
+					func(ok bool) {
+						if !ok || pub == nil {
+							return
+						}
+						if err := pub.GrantControl(peerID); err != nil {
+							statusDetail.SetText(err.Error())
+							return
+						}
+						revokeBtn.Enable()
+					}, w)
+			},
+		}
+
+		if clipboardCheck.Checked {
+			cfg.Clipboard = newFyneClipboard(w.Clipboard())
 		}
 
 		statusLabel.SetText("状态: 连接中")
 		statusDetail.SetText("正在注册 stream: " + streamID)
-		err = client.StartPublisher(streamID, capture, cfg, func(st client.PublisherStatus, detail string) {
+		pub, err = client.StartPublisher(streamID, src, cfg, func(st client.PublisherStatus, detail string) {
 			// 这里仅做轻量 UI 文本刷新，避免与 fyne 版本兼容问题。
 			statusLabel.SetText("状态: " + string(st))
 			statusDetail.SetText(detail)
 		})
 		if err != nil {
+			if closeSource != nil {
+				closeSource()
+				closeSource = nil
+			}
+			if injector != nil {
+				injector.Close()
+				injector = nil
+			}
 			statusLabel.SetText("状态: 错误")
 			statusDetail.SetText(err.Error())
 			return
 		}
 
 		running = true
+		go refreshPublisherStats(pub, statsLabel)
 		startBtn.Disable()
+		recordCtl.button.Enable()
 		stopBtn.Enable()
+		pauseBtn.Enable()
+		placeholderSelect.Disable()
+		remoteCheck.Disable()
+		burnCheck.Disable()
+		clipboardCheck.Disable()
+		clipboardUploadCheck.Disable()
+		snapshotCheck.Disable()
+		snapshotIntervalSelect.Disable()
+		clearAnnotationsBtn.Enable()
+		if clipboardCheck.Checked {
+			pushClipboardBtn.Enable()
+			pushImageBtn.Enable()
+		}
+		watermarkEntry.Disable()
+		watermarkAnchorSelect.Disable()
+		confidentialCheck.Disable()
+		logoEntry.Disable()
 		streamIDEntry.Disable()
 		screenSelect.Disable()
+		sourceSelect.Disable()
+		sourcePathEntry.Disable()
 		signalEntry.Disable()
 		fpsEntry.Disable()
+		codecSelect.Disable()
+		qualityEntry.Disable()
 		widthEntry.Disable()
 		heightEntry.Disable()
 		regionCheck.Disable()
@@ -207,11 +471,56 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 		yEntry.Disable()
 		rwEntry.Disable()
 		rhEntry.Disable()
+		iceOpts.setEnabled(false)
 	})
 
 	stopBtn = widget.NewButton("停止分享", func() {
 		log.Println("停止分享")
-		client.StopPublisher()
+		recordCtl.finish()
+		recordCtl.button.Disable()
+		if pub != nil {
+			pub.Stop()
+			pub = nil
+		}
+		if closeSource != nil {
+			closeSource()
+			closeSource = nil
+		}
+		if injector != nil {
+			injector.Close()
+			injector = nil
+		}
+		revokeBtn.Disable()
+		clearAnnotationsBtn.Disable()
+
+		pushClipboard := func(c clipboard.Content) {
+			if pub == nil {
+				return
+			}
+			n, err := pub.PushClipboard(c)
+			if err != nil {
+				statusDetail.SetText("推送剪贴板失败: " + err.Error())
+				return
+			}
+			statusDetail.SetText(fmt.Sprintf("已把剪贴板推送给 %d 个 Viewer", n))
+			notifyClipboard("剪贴板已推送", c)
+		}
+		pushClipboardBtn = widget.NewButton("推送剪贴板", func() {
+			c, err := newFyneClipboard(w.Clipboard()).Read()
+			if err != nil {
+				statusDetail.SetText("读取剪贴板失败: " + err.Error())
+				return
+			}
+			pushClipboard(c)
+		})
+		pushClipboardBtn.Disable()
+		pushImageBtn = widget.NewButton("推送图片…", func() {
+			pickClipboardImage(w, clipboard.Limits{}, pushClipboard)
+		})
+		pushImageBtn.Disable()
+		pushClipboardBtn.Disable()
+		pushImageBtn.Disable()
+		capture = nil
 		running = false
 		statusLabel.SetText("状态: 已停止")
 		statusDetail.SetText("已手动停止推流并释放资源")
@@ -230,10 +539,27 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 
 		startBtn.Enable()
 		stopBtn.Disable()
+		pauseBtn.SetText("暂停分享")
+		pauseBtn.Disable()
+		placeholderSelect.Enable()
+		remoteCheck.Enable()
+		burnCheck.Enable()
+		clipboardCheck.Enable()
+		clipboardUploadCheck.Enable()
+		snapshotCheck.Enable()
+		snapshotIntervalSelect.Enable()
+		watermarkEntry.Enable()
+		watermarkAnchorSelect.Enable()
+		confidentialCheck.Enable()
+		logoEntry.Enable()
 		streamIDEntry.Enable()
 		screenSelect.Enable()
+		sourceSelect.Enable()
+		sourcePathEntry.Enable()
 		signalEntry.Enable()
 		fpsEntry.Enable()
+		codecSelect.Enable()
+		qualityEntry.Enable()
 		widthEntry.Enable()
 		heightEntry.Enable()
 		regionCheck.Enable()
@@ -241,29 +567,235 @@ func RunPublisherUI(a fyne.App, w fyne.Window) {
 		yEntry.Enable()
 		rwEntry.Enable()
 		rhEntry.Enable()
+		iceOpts.setEnabled(true)
 	})
 	stopBtn.Disable()
 
+	// 暂停期间 Viewer 保持连接，只是看不到真实屏幕，适合中途输入密码等场景
+	pauseBtn = widget.NewButton("暂停分享", func() {
+		if pub == nil {
+			return
+		}
+		if pub.Paused() {
+			pub.Resume()
+			pauseBtn.SetText("暂停分享")
+		} else {
+			pub.Pause()
+			pauseBtn.SetText("恢复分享")
+		}
+	})
+	pauseBtn.Disable()
+
+	revokeBtn = widget.NewButton("收回控制", func() {
+		if pub != nil {
+			pub.RevokeControl()
+		}
+		revokeBtn.Disable()
+	})
+	revokeBtn.Disable()
+
+	clearAnnotationsBtn = widget.NewButton("清除全部标注", func() {
+		if pub != nil {
+			pub.ClearAnnotations()
+		}
+	})
+	clearAnnotationsBtn.Disable()
+
+	// 文件发送给当前所有 Viewer，也可以直接把文件拖进本窗口
+	sendFiles := func(paths []string) {
+		if pub == nil {
+			statusDetail.SetText("请先开始分享")
+			return
+		}
+		viewers := pub.Viewers()
+		if len(viewers) == 0 {
+			statusDetail.SetText("暂无 Viewer 连接，无法发送文件")
+			return
+		}
+		for _, path := range paths {
+			for _, peerID := range viewers {
+				if _, err := pub.SendFile(peerID, path); err != nil {
+					statusDetail.SetText("发送文件失败: " + err.Error())
+				}
+			}
+		}
+	}
+	sendFileBtn := widget.NewButton("发送文件…", func() {
+		pickFile(w, func(path string) { sendFiles([]string{path}) })
+	})
+	w.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		sendFiles(droppedPaths(uris))
+	})
+
+	applyMaskBtn := widget.NewButton("应用遮挡", func() {
+		masks, err := currentMasks()
+		if err != nil {
+			statusDetail.SetText(err.Error())
+			return
+		}
+		if capture != nil {
+			capture.SetMasks(masks)
+		}
+		statusDetail.SetText(fmt.Sprintf("已应用 %d 个遮挡区域", len(masks)))
+	})
+
 	content := container.NewVBox(
 		widget.NewLabel("Publisher 模式"),
 		widget.NewLabel("Stream ID"),
 		streamIDEntry,
 		widget.NewLabel("信令服务器"),
 		signalEntry,
+		widget.NewLabel("画面来源"),
+		container.NewGridWithColumns(2, sourceSelect, sourcePathEntry),
 		widget.NewLabel("屏幕选择"),
 		screenSelect,
 		widget.NewLabel("帧率"),
 		fpsEntry,
+		widget.NewLabel("画质"),
+		container.NewGridWithColumns(2, codecSelect, qualityEntry),
 		widget.NewLabel("输出分辨率"),
 		container.NewGridWithColumns(2, widthEntry, heightEntry),
 		regionCheck,
 		container.NewGridWithColumns(4, xEntry, yEntry, rwEntry, rhEntry),
+		widget.NewLabel("隐私遮挡"),
+		maskEntry,
+		container.NewGridWithColumns(3, maskPercent, maskStyleSelect, applyMaskBtn),
+		widget.NewLabel("水印"),
+		container.NewBorder(nil, nil, nil, watermarkAnchorSelect, watermarkEntry),
+		confidentialCheck,
+		logoEntry,
+		iceOpts.form(),
+		remoteCheck,
+		burnCheck,
+		container.NewGridWithColumns(2, clipboardCheck, clipboardUploadCheck),
+		container.NewGridWithColumns(2, snapshotCheck, snapshotIntervalSelect),
+		widget.NewLabel("暂停时的占位画面"),
+		placeholderSelect,
 		startBtn,
+		pauseBtn,
+		container.NewHBox(recordCtl.controls(), recordCtl.indicator),
+		revokeBtn,
+		clearAnnotationsBtn,
+		container.NewGridWithColumns(2, pushClipboardBtn, pushImageBtn),
+		sendFileBtn,
 		stopBtn,
 		statusLabel,
 		statusDetail,
+		widget.NewLabel("实时统计"),
+		statsLabel,
+		widget.NewLabel("文件传输（可把文件拖进窗口发送给所有 Viewer）"),
+		transfers.box,
 	)
-	w.SetContent(content)
+	w.SetContent(container.NewVScroll(content))
+}
+
+// refreshPublisherStats 每秒刷新一次统计面板，直到会话结束
+func refreshPublisherStats(pub *client.Publisher, label *widget.Label) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pub.Done():
+			return
+		case <-ticker.C:
+			label.SetText(formatPublisherStats(pub.Stats()))
+		}
+	}
+}
+
+func formatPublisherStats(st client.PublisherStats) string {
+	var b strings.Builder
+	p := st.Pipeline
+	fmt.Fprintf(&b, "流水线: %d 帧（丢弃 %d）  采集 %s  缩放 %s  水印 %s  编码 %s  帧大小 %d KB\n",
+		p.Frames, p.Dropped, fmtMillis(p.Capture), fmtMillis(p.Scale), fmtMillis(p.Overlay), fmtMillis(p.Encode), p.FrameBytes/1024)
+	if st.Annotations > 0 {
+		fmt.Fprintf(&b, "标注: %d 条\n", st.Annotations)
+	}
+	if len(st.Viewers) == 0 {
+		b.WriteString("暂无 Viewer 连接")
+		return b.String()
+	}
+	for _, v := range st.Viewers {
+		fmt.Fprintf(&b, "Viewer %s  [%s]  %s  RTT %s  端到端延迟 %s",
+			v.PeerID, v.ConnectionState, v.Codec, fmtMillis(v.RTT), fmtMillis(v.Latency))
+		if !v.Zoom.Full() {
+			fmt.Fprintf(&b, "  放大 %.1f×", v.Zoom.Factor())
+		}
+		if v.Recording {
+			b.WriteString("  录制中")
+		}
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "  已发送 %d 帧 / %.1f MB  跳过 %d  发送失败 %d  缓冲 %d KB\n",
+			v.FramesSent, float64(v.BytesSent)/(1<<20), v.Dropped, v.SendErrors, v.BufferedAmount/1024)
+		if v.LocalCandidate != "" {
+			fmt.Fprintf(&b, "  候选对 %s <-> %s\n", v.LocalCandidate, v.RemoteCandidate)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func fmtMillis(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// buildOverlayConfig 根据表单内容构造水印配置
+func buildOverlayConfig(text string, anchor overlay.Anchor, confidential bool, logoPath string) (overlay.Config, error) {
+	var cfg overlay.Config
+	if confidential {
+		cfg.Elements = append(cfg.Elements, overlay.Confidential())
+	}
+	if strings.TrimSpace(text) != "" {
+		el := overlay.Timestamp()
+		el.Text = text
+		el.Anchor = anchor
+		cfg.Elements = append(cfg.Elements, el)
+	}
+	if logoPath != "" {
+		f, err := os.Open(logoPath)
+		if err != nil {
+			return cfg, fmt.Errorf("读取 Logo 失败: %w", err)
+		}
+		defer f.Close()
+		logo, _, err := image.Decode(f)
+		if err != nil {
+			return cfg, fmt.Errorf("解析 Logo 失败: %w", err)
+		}
+		cfg.Elements = append(cfg.Elements, overlay.Element{
+			Image:   logo,
+			Anchor:  overlay.TopLeft,
+			Size:    0.08,
+			Opacity: 0.8,
+		})
+	}
+	return cfg, nil
+}
+
+// parseMasks 解析遮挡区域文本，每行 x,y,width,height
+func parseMasks(text string, percent bool, style screen.MaskStyle) ([]screen.Mask, error) {
+	var masks []screen.Mask
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, ",")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("第 %d 行遮挡区域格式错误，应为 x,y,width,height", i+1)
+		}
+		var v [4]float64
+		for j, p := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil || f < 0 {
+				return nil, fmt.Errorf("第 %d 行遮挡区域包含无效数值: %s", i+1, p)
+			}
+			v[j] = f
+		}
+		if v[2] <= 0 || v[3] <= 0 {
+			return nil, fmt.Errorf("第 %d 行遮挡区域 width/height 必须大于 0", i+1)
+		}
+		masks = append(masks, screen.Mask{X: v[0], Y: v[1], W: v[2], H: v[3], Percent: percent, Style: style})
+	}
+	return masks, nil
 }
 
 func parseIntOrDefault(text string, def int) (int, error) {
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"snap-screen/pkg/client"
+	"snap-screen/pkg/sink"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// rebroadcastMaxClients 为本地转发的客户端上限选项
+var rebroadcastMaxClients = []string{"1", "2", "4", "8", "16"}
+
+// rebroadcastControl 为观看窗口的本地转发：开启后在本地 HTTP 端口上把收到的画面转发为 MJPEG，
+// 供 OBS、VLC、网页等使用。它包装显示画面的 sink，转发的始终是直播画面，不受回看暂停影响
+type rebroadcastControl struct {
+	next     client.FrameSink
+	win      fyne.Window
+	onStatus func(string)
+
+	button *widget.Button
+	// info 显示转发地址和客户端数，未转发时隐藏
+	info *widget.Label
+
+	// 上次使用的设置，再次开启时沿用
+	addr       string
+	maxClients string
+	username   string
+	password   string
+
+	mu   sync.Mutex
+	srv  *sink.MJPEGServer
+	quit chan struct{}
+}
+
+// newRebroadcastControl 创建本地转发控件，next 为显示画面的 sink
+func newRebroadcastControl(win fyne.Window, next client.FrameSink, onStatus func(string)) *rebroadcastControl {
+	c := &rebroadcastControl{
+		next:       next,
+		win:        win,
+		onStatus:   onStatus,
+		addr:       sink.DefaultMJPEGAddr,
+		maxClients: fmt.Sprint(sink.DefaultMJPEGMaxClients),
+	}
+	c.button = widget.NewButton("本地转发…", c.toggle)
+	c.info = widget.NewLabel("")
+	c.info.Hide()
+	return c
+}
+
+var _ client.FrameSink = (*rebroadcastControl)(nil)
+
+// WriteFrame 实现 client.FrameSink：交给显示画面的 sink，正在转发时同时交给 MJPEG 服务
+func (c *rebroadcastControl) WriteFrame(f client.Frame) error {
+	c.mu.Lock()
+	srv := c.srv
+	c.mu.Unlock()
+	if srv != nil {
+		srv.WriteFrame(f)
+	}
+	return c.next.WriteFrame(f)
+}
+
+// controls 返回按钮和转发地址
+func (c *rebroadcastControl) controls() fyne.CanvasObject {
+	return container.NewHBox(c.button, c.info)
+}
+
+func (c *rebroadcastControl) toggle() {
+	c.mu.Lock()
+	running := c.srv != nil
+	c.mu.Unlock()
+	if running {
+		c.finish()
+		c.onStatus("本地转发已停止")
+		return
+	}
+
+	addrEntry := widget.NewEntry()
+	addrEntry.SetText(c.addr)
+	clientsSelect := widget.NewSelect(rebroadcastMaxClients, nil)
+	clientsSelect.SetSelected(c.maxClients)
+	userEntry := widget.NewEntry()
+	userEntry.SetText(c.username)
+	userEntry.SetPlaceHolder("留空则不需要认证")
+	passEntry := widget.NewPasswordEntry()
+	passEntry.SetText(c.password)
+	dialog.ShowForm("本地转发为 MJPEG", "开始转发", "取消",
+		[]*widget.FormItem{
+			widget.NewFormItem("监听地址", addrEntry),
+			widget.NewFormItem("客户端上限", clientsSelect),
+			widget.NewFormItem("用户名", userEntry),
+			widget.NewFormItem("密码", passEntry),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			c.addr, c.maxClients = strings.TrimSpace(addrEntry.Text), clientsSelect.Selected
+			c.username, c.password = userEntry.Text, passEntry.Text
+			c.start()
+		}, c.win)
+}
+
+// start 按当前设置开始转发
+func (c *rebroadcastControl) start() {
+	var maxClients int
+	fmt.Sscan(c.maxClients, &maxClients)
+	srv, err := sink.NewMJPEGServer(sink.MJPEGServerOptions{
+		Addr:       c.addr,
+		MaxClients: maxClients,
+		Username:   c.username,
+		Password:   c.password,
+	})
+	if err != nil {
+		c.onStatus("开启本地转发失败: " + err.Error())
+		return
+	}
+	quit := make(chan struct{})
+	c.mu.Lock()
+	c.srv, c.quit = srv, quit
+	c.mu.Unlock()
+
+	c.button.SetText("停止转发")
+	c.win.Clipboard().SetContent(srv.URL())
+	c.onStatus("本地转发已开启，地址已复制: " + srv.URL())
+	c.updateInfo(srv)
+	c.info.Show()
+	go c.tick(srv, quit)
+}
+
+// finish 停止转发，未在转发时什么也不做；关闭窗口时调用
+func (c *rebroadcastControl) finish() {
+	c.mu.Lock()
+	srv, quit := c.srv, c.quit
+	c.srv, c.quit = nil, nil
+	c.mu.Unlock()
+	if srv == nil {
+		return
+	}
+	close(quit)
+	srv.Close()
+	c.button.SetText("本地转发…")
+	c.info.Hide()
+}
+
+// tick 每秒刷新客户端数
+func (c *rebroadcastControl) tick(srv *sink.MJPEGServer, quit chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			c.updateInfo(srv)
+		}
+	}
+}
+
+func (c *rebroadcastControl) updateInfo(srv *sink.MJPEGServer) {
+	c.info.SetText(fmt.Sprintf("%s（%d 个客户端）", srv.URL(), srv.Clients()))
+}
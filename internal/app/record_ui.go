@@ -0,0 +1,180 @@
+package app
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"snap-screen/pkg/client"
+	"snap-screen/pkg/sink"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// recordSplits 为录制的分段方式
+var recordSplits = []struct {
+	label    string
+	size     int64
+	duration time.Duration
+}{
+	{"不分段", 0, 0},
+	{"每 10 分钟一段", 0, 10 * time.Minute},
+	{"每 30 分钟一段", 0, 30 * time.Minute},
+	{"每 100 MB 一段", 100 << 20, 0},
+	{"每 500 MB 一段", 500 << 20, 0},
+}
+
+// recordFilePrefix 把 stream ID 等转换为可以用作文件名前缀的文字
+func recordFilePrefix(kind, name string) string {
+	return kind + "-" + strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// recordingDir 返回录制文件的保存目录
+func recordingDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "snap-screen", "recordings")
+	}
+	return filepath.Join(home, "Videos", "snap-screen")
+}
+
+// recordControl 为观看窗口和 Publisher 共用的录制按钮、分段选项和「录制中」提示。
+// start / stop 对应 Viewer 或 Publisher 的 StartRecording / StopRecording
+type recordControl struct {
+	prefix   string
+	start    func(client.FrameSink)
+	stop     func() client.FrameSink
+	onStatus func(string)
+
+	button *widget.Button
+	split  *widget.Select
+	// indicator 为红色的「● 录制中 00:00」，不录制时隐藏
+	indicator *canvas.Text
+
+	mu      sync.Mutex
+	rec     *sink.AVI
+	started time.Time
+	quit    chan struct{}
+}
+
+// newRecordControl 创建录制控件，prefix 为文件名前缀，onStatus 用于显示开始、结束和出错的提示
+func newRecordControl(prefix string, start func(client.FrameSink), stop func() client.FrameSink, onStatus func(string)) *recordControl {
+	c := &recordControl{prefix: prefix, start: start, stop: stop, onStatus: onStatus}
+	c.button = widget.NewButton("开始录制", c.toggle)
+	labels := make([]string, 0, len(recordSplits))
+	for _, s := range recordSplits {
+		labels = append(labels, s.label)
+	}
+	c.split = widget.NewSelect(labels, nil)
+	c.split.SetSelected(labels[0])
+	c.indicator = canvas.NewText("", color.NRGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff})
+	c.indicator.TextStyle = fyne.TextStyle{Bold: true}
+	c.indicator.Hide()
+	return c
+}
+
+// controls 返回按钮和分段选项
+func (c *recordControl) controls() fyne.CanvasObject {
+	return container.NewHBox(c.button, c.split)
+}
+
+// recording 返回是否正在录制
+func (c *recordControl) recording() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rec != nil
+}
+
+func (c *recordControl) toggle() {
+	if c.recording() {
+		c.finish()
+		return
+	}
+	opts := sink.AVIOptions{
+		Prefix: c.prefix,
+		OnSegment: func(path string, err error) {
+			if err != nil {
+				c.onStatus("录制文件收尾失败: " + err.Error())
+				return
+			}
+			c.onStatus("录制已保存到 " + path)
+		},
+	}
+	for _, s := range recordSplits {
+		if s.label == c.split.Selected {
+			opts.MaxSize, opts.MaxDuration = s.size, s.duration
+		}
+	}
+	dir := recordingDir()
+	rec, err := sink.NewAVI(dir, opts)
+	if err != nil {
+		c.onStatus("开始录制失败: " + err.Error())
+		return
+	}
+	quit := make(chan struct{})
+	c.mu.Lock()
+	c.rec, c.started, c.quit = rec, time.Now(), quit
+	c.mu.Unlock()
+	c.start(rec)
+
+	c.button.SetText("停止录制")
+	c.split.Disable()
+	c.onStatus("正在录制到 " + dir)
+	c.updateIndicator()
+	c.indicator.Show()
+	go c.tick(quit)
+}
+
+// finish 停止录制并写完最后一段，未在录制时什么也不做；关闭窗口、停止分享时调用
+func (c *recordControl) finish() {
+	c.mu.Lock()
+	rec, quit := c.rec, c.quit
+	c.rec, c.quit = nil, nil
+	c.mu.Unlock()
+	if rec == nil {
+		return
+	}
+	close(quit)
+	c.stop()
+	if err := rec.Close(); err != nil {
+		c.onStatus("结束录制失败: " + err.Error())
+	}
+	c.button.SetText("开始录制")
+	c.split.Enable()
+	c.indicator.Hide()
+}
+
+// tick 每秒刷新录制时长
+func (c *recordControl) tick(quit chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			c.updateIndicator()
+		}
+	}
+}
+
+func (c *recordControl) updateIndicator() {
+	c.mu.Lock()
+	elapsed := time.Since(c.started)
+	c.mu.Unlock()
+	s := int(elapsed.Seconds())
+	c.indicator.Text = fmt.Sprintf("● 录制中 %02d:%02d:%02d", s/3600, s/60%60, s%60)
+	c.indicator.Refresh()
+}
@@ -0,0 +1,451 @@
+package app
+
+import (
+	"image"
+	"log"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"snap-screen/pkg/annotate"
+	"snap-screen/pkg/client"
+	"snap-screen/pkg/input"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// remoteView 包装显示远程画面的 canvas.Image，在获得远程控制权后
+// 把鼠标、滚轮和键盘事件换算为画面归一化坐标发给 Publisher；
+// 选中标注工具时鼠标改为在画面上绘制标注，标注画在图片上方的透明图层中；
+// 两者都未开启时滚轮放大 / 缩小、左键拖动平移，由 Publisher 按原始分辨率发送放大后的区域
+type remoteView struct {
+	widget.BaseWidget
+	img *canvas.Image
+	win fyne.Window
+	// viewer 为本窗口的会话，在窗口显示之前设置，之后只读
+	viewer *client.Viewer
+
+	// 由 OnRemoteControl 回调（DataChannel 协程）写、UI 事件读
+	enabled atomic.Bool
+
+	// 标注图层，board 由会话从 Publisher 同步
+	board    *annotate.Board
+	renderer *annotate.Renderer
+	layer    *canvas.Raster
+
+	// 以下标注工具状态只在 UI 事件中读写；tool 为空表示未选中工具
+	tool  annotate.Kind
+	color string
+	ttl   time.Duration
+
+	// draft 为正在绘制、尚未松开鼠标的标注，图层渲染时也会读取
+	draftMu sync.Mutex
+	draft   *annotate.Annotation
+
+	// shown 为当前画面对应的区域（由 OnZoom 回调写），want 为最近请求的区域；
+	// 请求经 zoomTimer 合并，拖动平移时不会每个鼠标事件都发一次
+	zoomMu    sync.Mutex
+	shown     client.ZoomRegion
+	want      client.ZoomRegion
+	zoomTimer *time.Timer
+
+	// 平移拖动的起点，只在 UI 事件中读写
+	panning  bool
+	panFrom  fyne.Position
+	panStart client.ZoomRegion
+}
+
+// 合并放大请求的间隔
+const zoomRequestDelay = 80 * time.Millisecond
+
+func newRemoteView(img *canvas.Image, board *annotate.Board, win fyne.Window) *remoteView {
+	v := &remoteView{img: img, win: win, board: board, color: "#e53935"}
+	r, err := annotate.NewRenderer()
+	if err != nil {
+		log.Println("create annotation renderer failed:", err)
+	}
+	v.renderer = r
+	v.layer = canvas.NewRaster(v.renderAnnotations)
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *remoteView) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(v.img, v.layer))
+}
+
+// setTool 切换标注工具，kind 为空时恢复为远程控制 / 普通观看
+func (v *remoteView) setTool(kind annotate.Kind, color string, ttl time.Duration) {
+	v.tool, v.color, v.ttl = kind, color, ttl
+	v.setDraft(nil)
+}
+
+func (v *remoteView) setDraft(a *annotate.Annotation) {
+	v.draftMu.Lock()
+	v.draft = a
+	v.draftMu.Unlock()
+	v.layer.Refresh()
+}
+
+// watchAnnotations 在标注变化或有标注正在淡出时以约 10fps 重绘图层，直到 stop 关闭
+func (v *remoteView) watchAnnotations(stop <-chan struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	var version uint64
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if ver := v.board.Version(); ver != version || v.board.Fading(now) {
+				version = ver
+				v.layer.Refresh()
+			}
+		}
+	}
+}
+
+// renderAnnotations 为标注图层生成 w×h 像素的透明图像，画面区域与图片的等比居中一致
+func (v *remoteView) renderAnnotations(w, h int) image.Image {
+	now := time.Now()
+	anns := v.board.Snapshot(now)
+	v.draftMu.Lock()
+	if v.draft != nil {
+		anns = append(anns, *v.draft)
+	}
+	v.draftMu.Unlock()
+
+	src := v.img.Image
+	if len(anns) == 0 || src == nil || w <= 0 || h <= 0 {
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	}
+	b := src.Bounds()
+	if b.Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	}
+	scale := math.Min(float64(w)/float64(b.Dx()), float64(h)/float64(b.Dy()))
+	dw, dh := int(float64(b.Dx())*scale), int(float64(b.Dy())*scale)
+	x0, y0 := (w-dw)/2, (h-dh)/2
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	// 放大时完整画面超出图层，渲染器只绘制落在图层内的部分
+	area := v.shownZoom().FullArea(image.Rect(x0, y0, x0+dw, y0+dh))
+	v.renderer.Render(dst, area, anns, now)
+	return dst
+}
+
+// setShownZoom 由 OnZoom 回调驱动，记录当前画面对应的区域
+func (v *remoteView) setShownZoom(r client.ZoomRegion) {
+	v.zoomMu.Lock()
+	v.shown = r
+	v.zoomMu.Unlock()
+	v.layer.Refresh()
+}
+
+func (v *remoteView) shownZoom() client.ZoomRegion {
+	v.zoomMu.Lock()
+	defer v.zoomMu.Unlock()
+	return v.shown
+}
+
+// zoomBy 以 pos 处的画面内容为中心放大 factor 倍（小于 1 为缩小），pos 不在画面上时以当前中心为准
+func (v *remoteView) zoomBy(factor float64, pos *fyne.Position) {
+	v.zoomMu.Lock()
+	cur := v.want
+	v.zoomMu.Unlock()
+	if cur.Full() {
+		cur = client.ZoomRegion{W: 1, H: 1}
+	}
+	// 锚点在放大前后保持在窗口中的同一位置
+	ax, ay := cur.X+cur.W/2, cur.Y+cur.H/2
+	if pos != nil {
+		if x, y, inside := v.normalize(*pos); inside {
+			ax, ay = x, y
+		}
+	}
+	w, h := cur.W/factor, cur.H/factor
+	v.requestZoom(client.ZoomRegion{
+		X: ax - (ax-cur.X)*w/cur.W,
+		Y: ay - (ay-cur.Y)*h/cur.H,
+		W: w,
+		H: h,
+	})
+}
+
+// resetZoom 恢复完整画面
+func (v *remoteView) resetZoom() {
+	v.requestZoom(client.ZoomRegion{})
+}
+
+// requestZoom 记录想看的区域，稍后统一发给 Publisher
+func (v *remoteView) requestZoom(r client.ZoomRegion) {
+	v.zoomMu.Lock()
+	defer v.zoomMu.Unlock()
+	v.want = r.Normalize()
+	if v.zoomTimer != nil {
+		return
+	}
+	v.zoomTimer = time.AfterFunc(zoomRequestDelay, func() {
+		v.zoomMu.Lock()
+		r := v.want
+		v.zoomTimer = nil
+		v.zoomMu.Unlock()
+		if err := v.viewer.SetZoom(r); err != nil {
+			log.Println("request zoom failed:", err)
+		}
+	})
+}
+
+// panTo 按拖动距离平移放大区域，拖动方向与画面移动方向一致
+func (v *remoteView) panTo(pos fyne.Position) {
+	_, _, dw, dh := v.imageRect()
+	if dw <= 0 || dh <= 0 {
+		return
+	}
+	r := v.panStart
+	r.X -= float64((pos.X-v.panFrom.X)/dw) * r.W
+	r.Y -= float64((pos.Y-v.panFrom.Y)/dh) * r.H
+	v.requestZoom(r)
+}
+
+// 标注鼠标处理：按下开始、移动更新、松开后发给 Publisher，经转发回来后出现在 board 中
+func (v *remoteView) annotateDown(pos fyne.Position) {
+	x, y, _ := v.normalize(pos)
+	p := annotate.Point{X: x, Y: y}
+	a := &annotate.Annotation{Kind: v.tool, Color: v.color, TTL: v.ttl}
+	if v.tool == annotate.Text {
+		entry := widget.NewEntry()
+		dialog.ShowForm("添加文字标注", "添加", "取消",
+			[]*widget.FormItem{widget.NewFormItem("文字", entry)},
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				a.Points, a.Text = []annotate.Point{p}, entry.Text
+				v.submit(*a)
+			}, v.win)
+		return
+	}
+	if v.tool == annotate.Stroke {
+		a.Points = []annotate.Point{p}
+	} else {
+		a.Points = []annotate.Point{p, p}
+	}
+	v.setDraft(a)
+}
+
+func (v *remoteView) annotateMove(pos fyne.Position) {
+	v.draftMu.Lock()
+	a := v.draft
+	if a == nil {
+		v.draftMu.Unlock()
+		return
+	}
+	x, y, _ := v.normalize(pos)
+	p := annotate.Point{X: min(max(x, 0), 1), Y: min(max(y, 0), 1)}
+	if a.Kind == annotate.Stroke {
+		// 移动距离过小的采样点对形状没有贡献，只会增大消息体积
+		last := a.Points[len(a.Points)-1]
+		if math.Hypot(p.X-last.X, p.Y-last.Y) < 0.003 || len(a.Points) >= annotate.MaxPoints {
+			v.draftMu.Unlock()
+			return
+		}
+		a.Points = append(a.Points, p)
+	} else {
+		a.Points[1] = p
+	}
+	v.draftMu.Unlock()
+	v.layer.Refresh()
+}
+
+func (v *remoteView) annotateUp() {
+	v.draftMu.Lock()
+	a := v.draft
+	v.draftMu.Unlock()
+	v.setDraft(nil)
+	if a != nil {
+		v.submit(*a)
+	}
+}
+
+func (v *remoteView) submit(a annotate.Annotation) {
+	if err := v.viewer.SendAnnotation(a); err != nil {
+		log.Println("send annotation failed:", err)
+	}
+}
+
+// setEnabled 开启 / 关闭事件转发，由 OnRemoteControl 回调驱动
+func (v *remoteView) setEnabled(enabled bool) {
+	v.enabled.Store(enabled)
+	if enabled {
+		if c := fyne.CurrentApp().Driver().CanvasForObject(v); c != nil {
+			c.Focus(v)
+		}
+	}
+}
+
+// imageRect 返回图片在控件内的显示位置和大小，图片为空时宽高为 0
+func (v *remoteView) imageRect() (x0, y0, dw, dh float32) {
+	if v.img.Image == nil {
+		return 0, 0, 0, 0
+	}
+	b := v.img.Image.Bounds()
+	size := v.Size()
+	if b.Dx() == 0 || b.Dy() == 0 || size.Width <= 0 || size.Height <= 0 {
+		return 0, 0, 0, 0
+	}
+	scale := min(size.Width/float32(b.Dx()), size.Height/float32(b.Dy()))
+	dw, dh = float32(b.Dx())*scale, float32(b.Dy())*scale
+	return (size.Width - dw) / 2, (size.Height - dh) / 2, dw, dh
+}
+
+// normalize 把控件内坐标换算为完整画面内的归一化坐标（放大时按当前区域换算）。
+// 图片按 ImageFillContain 等比居中显示，落在两侧留白处时返回 false。
+func (v *remoteView) normalize(pos fyne.Position) (float64, float64, bool) {
+	x0, y0, dw, dh := v.imageRect()
+	if dw <= 0 || dh <= 0 {
+		return 0, 0, false
+	}
+	x := float64((pos.X - x0) / dw)
+	y := float64((pos.Y - y0) / dh)
+	inside := x >= 0 && x <= 1 && y >= 0 && y <= 1
+	x, y = v.shownZoom().Map(x, y)
+	return x, y, inside
+}
+
+func (v *remoteView) send(ev client.InputEvent) {
+	if !v.enabled.Load() {
+		return
+	}
+	if err := v.viewer.SendInput(ev); err != nil {
+		log.Println("send input failed:", err)
+	}
+}
+
+func (v *remoteView) sendPointer(typ input.EventType, pos fyne.Position, button input.MouseButton) {
+	x, y, inside := v.normalize(pos)
+	// 抬起事件即使落在画面外也要发送，否则对端按键会一直处于按下状态
+	if !inside && typ != input.MouseUp {
+		return
+	}
+	v.send(client.InputEvent{Type: typ, X: x, Y: y, Button: button})
+}
+
+func mouseButton(b desktop.MouseButton) input.MouseButton {
+	switch b {
+	case desktop.MouseButtonSecondary:
+		return input.ButtonRight
+	case desktop.MouseButtonTertiary:
+		return input.ButtonMiddle
+	}
+	return input.ButtonLeft
+}
+
+// MouseDown / MouseUp 实现 desktop.Mouseable
+func (v *remoteView) MouseDown(e *desktop.MouseEvent) {
+	// 选中标注工具时左键用于绘制，不转发给对端
+	if v.tool != "" && e.Button == desktop.MouseButtonPrimary {
+		if _, _, inside := v.normalize(e.Position); inside {
+			v.annotateDown(e.Position)
+		}
+		return
+	}
+	if v.enabled.Load() {
+		if c := fyne.CurrentApp().Driver().CanvasForObject(v); c != nil {
+			c.Focus(v)
+		}
+	} else if e.Button == desktop.MouseButtonPrimary {
+		v.zoomMu.Lock()
+		v.panStart = v.want
+		v.zoomMu.Unlock()
+		v.panning, v.panFrom = !v.panStart.Full(), e.Position
+		return
+	}
+	v.sendPointer(input.MouseDown, e.Position, mouseButton(e.Button))
+}
+
+func (v *remoteView) MouseUp(e *desktop.MouseEvent) {
+	if v.tool != "" && e.Button == desktop.MouseButtonPrimary {
+		v.annotateUp()
+		return
+	}
+	if v.panning && e.Button == desktop.MouseButtonPrimary {
+		v.panning = false
+		return
+	}
+	v.sendPointer(input.MouseUp, e.Position, mouseButton(e.Button))
+}
+
+// MouseIn / MouseMoved / MouseOut 实现 desktop.Hoverable
+func (v *remoteView) MouseIn(e *desktop.MouseEvent) {}
+
+func (v *remoteView) MouseMoved(e *desktop.MouseEvent) {
+	if v.tool != "" {
+		v.annotateMove(e.Position)
+		return
+	}
+	if v.panning {
+		v.panTo(e.Position)
+		return
+	}
+	v.sendPointer(input.MouseMove, e.Position, 0)
+}
+
+func (v *remoteView) MouseOut() {}
+
+// Scrolled 实现 fyne.Scrollable，fyne 的滚动量以像素计，这里约 40 像素折算为一格。
+// 获得控制权时滚轮转发给对端，否则用于放大 / 缩小
+func (v *remoteView) Scrolled(e *fyne.ScrollEvent) {
+	if !v.enabled.Load() {
+		if e.Scrolled.DY != 0 {
+			v.zoomBy(math.Pow(1.25, float64(e.Scrolled.DY/40)), &e.Position)
+		}
+		return
+	}
+	dx, dy := int(-e.Scrolled.DX/40), int(-e.Scrolled.DY/40)
+	if dx == 0 && e.Scrolled.DX != 0 {
+		dx = -sign(e.Scrolled.DX)
+	}
+	if dy == 0 && e.Scrolled.DY != 0 {
+		dy = -sign(e.Scrolled.DY)
+	}
+	v.send(client.InputEvent{Type: input.Scroll, DX: dx, DY: dy})
+}
+
+func sign(f float32) int {
+	if f < 0 {
+		return -1
+	}
+	return 1
+}
+
+// FocusGained / FocusLost / TypedRune / TypedKey 实现 fyne.Focusable；
+// 实际按键通过 desktop.Keyable 的 KeyDown / KeyUp 发送，保留按下与抬起的时序
+func (v *remoteView) FocusGained() {}
+
+func (v *remoteView) FocusLost() {}
+
+func (v *remoteView) TypedRune(rune) {}
+
+func (v *remoteView) TypedKey(*fyne.KeyEvent) {}
+
+func (v *remoteView) KeyDown(e *fyne.KeyEvent) {
+	v.send(client.InputEvent{Type: input.KeyDown, Key: string(e.Name)})
+}
+
+func (v *remoteView) KeyUp(e *fyne.KeyEvent) {
+	v.send(client.InputEvent{Type: input.KeyUp, Key: string(e.Name)})
+}
+
+var (
+	_ desktop.Mouseable = (*remoteView)(nil)
+	_ desktop.Hoverable = (*remoteView)(nil)
+	_ desktop.Keyable   = (*remoteView)(nil)
+	_ fyne.Scrollable   = (*remoteView)(nil)
+)
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// showSnapshot 预览收到的原始分辨率截图，可另存到其他位置
+func showSnapshot(w fyne.Window, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	hint := "已保存到 " + path
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		hint = fmt.Sprintf("原始分辨率 %d×%d，%s", cfg.Width, cfg.Height, hint)
+	}
+	showImage(w, "原始分辨率截图", hint, filepath.Base(path), data, fyne.NewSize(640, 400))
+}
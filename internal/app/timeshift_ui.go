@@ -0,0 +1,274 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"snap-screen/pkg/client"
+	"snap-screen/pkg/sink"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// timeShiftDurations 为回看缓冲的时长选项，0 表示不开启回看
+var timeShiftDurations = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"不回看", 0},
+	{"回看 30 秒", 30 * time.Second},
+	{"回看 1 分钟", time.Minute},
+	{"回看 5 分钟", 5 * time.Minute},
+}
+
+// timeShiftSizes 为回看缓冲的内存上限选项
+var timeShiftSizes = []struct {
+	label string
+	size  int64
+}{
+	{"内存上限 100 MB", 100 << 20},
+	{"内存上限 256 MB", 256 << 20},
+	{"内存上限 1 GB", 1 << 30},
+}
+
+// clipLengths 为「保存片段」的时长选项
+var clipLengths = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"最近 10 秒", 10 * time.Second},
+	{"最近 30 秒", 30 * time.Second},
+	{"最近 1 分钟", time.Minute},
+	{"最近 5 分钟", 5 * time.Minute},
+}
+
+// timeShiftSeekStep 为「后退」按钮每次后退的时长
+const timeShiftSeekStep = 5 * time.Second
+
+// timeShift 为观看窗口的回看：收到的每一帧都进入 sink.Ring，直播时同时交给 live 显示；
+// 暂停或往回拖动进度条后画面停在缓冲中的某一帧，后台照常收帧，「回到直播」后继续显示最新画面
+type timeShift struct {
+	ring     *sink.Ring
+	live     client.FrameSink
+	prefix   string
+	onStatus func(string)
+
+	pauseBtn   *widget.Button
+	backBtn    *widget.Button
+	liveBtn    *widget.Button
+	slider     *widget.Slider
+	position   *widget.Label
+	clipSelect *widget.Select
+	saveBtn    *widget.Button
+
+	mu     sync.Mutex
+	paused bool
+	// shown 为暂停时正在显示的帧的收到时间
+	shown time.Time
+	quit  chan struct{}
+}
+
+// newTimeShift 创建回看控件，live 为直播时显示画面的 sink，prefix 为保存片段的文件名前缀
+func newTimeShift(opts sink.RingOptions, live client.FrameSink, prefix string, onStatus func(string)) *timeShift {
+	t := &timeShift{ring: sink.NewRing(opts), live: live, prefix: prefix, onStatus: onStatus, quit: make(chan struct{})}
+	t.pauseBtn = widget.NewButton("暂停", t.pause)
+	t.backBtn = widget.NewButton(fmt.Sprintf("后退 %d 秒", int(timeShiftSeekStep.Seconds())), func() {
+		t.seekBy(-timeShiftSeekStep)
+	})
+	t.liveBtn = widget.NewButton("回到直播", t.goLive)
+	t.liveBtn.Disable()
+	// 进度条的值为相对最新一帧的秒数，拖到最右端回到直播
+	t.slider = widget.NewSlider(-1, 0)
+	t.slider.Step = 0.1
+	t.slider.OnChanged = func(v float64) {
+		if v >= 0 {
+			t.goLive()
+			return
+		}
+		if _, newest, ok := t.ring.Span(); ok {
+			t.seek(newest.Add(time.Duration(v * float64(time.Second))))
+		}
+	}
+	t.position = widget.NewLabel("直播")
+	labels := make([]string, 0, len(clipLengths))
+	for _, c := range clipLengths {
+		labels = append(labels, c.label)
+	}
+	t.clipSelect = widget.NewSelect(labels, nil)
+	t.clipSelect.SetSelected(labels[0])
+	t.saveBtn = widget.NewButton("保存片段", t.saveClip)
+	go t.tick()
+	return t
+}
+
+var _ client.FrameSink = (*timeShift)(nil)
+
+// WriteFrame 实现 client.FrameSink：缓冲每一帧，未暂停时显示
+func (t *timeShift) WriteFrame(f client.Frame) error {
+	t.ring.WriteFrame(f)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.paused {
+		return nil
+	}
+	return t.live.WriteFrame(f)
+}
+
+// controls 返回回看的按钮、进度条和保存片段的选项
+func (t *timeShift) controls() fyne.CanvasObject {
+	return container.NewBorder(nil, nil,
+		container.NewHBox(t.pauseBtn, t.backBtn, t.liveBtn),
+		container.NewHBox(t.position, widget.NewSeparator(), t.clipSelect, t.saveBtn),
+		t.slider)
+}
+
+// pause 停在当前显示的画面
+func (t *timeShift) pause() {
+	_, newest, ok := t.ring.Span()
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	if !t.paused {
+		t.paused, t.shown = true, newest
+	}
+	t.mu.Unlock()
+	t.updateControls()
+}
+
+// seekBy 从当前显示的画面前后移动 d，直播时从最新一帧算起
+func (t *timeShift) seekBy(d time.Duration) {
+	_, newest, ok := t.ring.Span()
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	from := newest
+	if t.paused {
+		from = t.shown
+	}
+	t.mu.Unlock()
+	t.seek(from.Add(d))
+}
+
+// seek 暂停并显示缓冲中 at 时刻的画面，超出缓冲范围时显示最旧的一帧
+func (t *timeShift) seek(at time.Time) {
+	rf, ok := t.ring.At(at)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	t.paused, t.shown = true, rf.Received
+	t.mu.Unlock()
+	t.updateControls()
+
+	img, err := rf.Image()
+	if err != nil {
+		t.onStatus("回看画面解码失败: " + err.Error())
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// 解码期间已经回到直播或换了位置时不再显示
+	if t.paused && t.shown.Equal(rf.Received) {
+		t.live.WriteFrame(client.Frame{Image: img, Seq: rf.Seq, Received: rf.Received, Region: rf.Region, JPEG: rf.JPEG})
+	}
+}
+
+// goLive 回到直播，立即显示缓冲中最新的一帧，不必等下一帧到达
+func (t *timeShift) goLive() {
+	t.mu.Lock()
+	t.paused = false
+	t.mu.Unlock()
+	t.updateControls()
+
+	_, newest, ok := t.ring.Span()
+	if !ok {
+		return
+	}
+	rf, _ := t.ring.At(newest)
+	img, err := rf.Image()
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.paused {
+		t.live.WriteFrame(client.Frame{Image: img, Seq: rf.Seq, Received: rf.Received, Region: rf.Region, JPEG: rf.JPEG})
+	}
+}
+
+// saveClip 把缓冲中最近一段画面保存为 AVI
+func (t *timeShift) saveClip() {
+	d := clipLengths[0].duration
+	for _, c := range clipLengths {
+		if c.label == t.clipSelect.Selected {
+			d = c.duration
+		}
+	}
+	t.saveBtn.Disable()
+	go func() {
+		defer t.saveBtn.Enable()
+		path, err := t.ring.SaveClip(recordingDir(), t.prefix, d)
+		if err != nil {
+			t.onStatus("保存片段失败: " + err.Error())
+			return
+		}
+		t.onStatus("片段已保存到 " + path)
+	}()
+}
+
+// tick 定期刷新进度条范围和位置，缓冲随时间增长、暂停时最新一帧不断后移
+func (t *timeShift) tick() {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.quit:
+			return
+		case <-ticker.C:
+			t.updateControls()
+		}
+	}
+}
+
+// updateControls 按缓冲和暂停状态刷新按钮、进度条和位置文字
+func (t *timeShift) updateControls() {
+	oldest, newest, ok := t.ring.Span()
+	t.mu.Lock()
+	paused, shown := t.paused, t.shown
+	t.mu.Unlock()
+
+	span := newest.Sub(oldest).Seconds()
+	// 不直接 SetValue，避免触发 OnChanged
+	t.slider.Min = -max(span, 1)
+	t.slider.Value = 0
+	if paused {
+		t.slider.Value = max(shown.Sub(newest).Seconds(), t.slider.Min)
+	}
+	t.slider.Refresh()
+
+	_, size := t.ring.Len()
+	buffered := fmt.Sprintf("已缓冲 %.0f 秒 / %.1f MB", span, float64(size)/(1<<20))
+	if !ok {
+		buffered = "尚未缓冲画面"
+	}
+	if paused {
+		t.pauseBtn.Disable()
+		t.liveBtn.Enable()
+		t.position.SetText(fmt.Sprintf("回看 %.1f 秒前（%s）  %s", newest.Sub(shown).Seconds(), shown.Format("15:04:05"), buffered))
+	} else {
+		t.pauseBtn.Enable()
+		t.liveBtn.Disable()
+		t.position.SetText("直播  " + buffered)
+	}
+}
+
+// close 停止刷新并释放缓冲，关闭观看窗口时调用
+func (t *timeShift) close() {
+	close(t.quit)
+	t.ring.Close()
+}
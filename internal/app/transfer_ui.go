@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"snap-screen/pkg/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 最多显示的传输条目，超出时移除最早结束的
+const maxTransferRows = 5
+
+// transferPanel 以进度条列出最近的文件收发
+type transferPanel struct {
+	box *fyne.Container
+
+	mu    sync.Mutex
+	rows  map[string]*transferRow
+	order []string
+}
+
+type transferRow struct {
+	label    *widget.Label
+	bar      *widget.ProgressBar
+	obj      fyne.CanvasObject
+	finished bool
+}
+
+func newTransferPanel() *transferPanel {
+	return &transferPanel{box: container.NewVBox(), rows: make(map[string]*transferRow)}
+}
+
+// update 由 OnTransfer 回调调用，peer 为对方描述
+func (t *transferPanel) update(peer string, p transfer.Progress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	row, ok := t.rows[p.ID]
+	if !ok {
+		row = &transferRow{label: widget.NewLabel(""), bar: widget.NewProgressBar()}
+		row.obj = container.NewVBox(row.label, row.bar)
+		t.rows[p.ID] = row
+		t.order = append(t.order, p.ID)
+		t.box.Add(row.obj)
+		t.trim()
+	}
+	row.finished = p.State.Finished()
+	row.label.SetText(describeTransfer(peer, p))
+	if p.Size > 0 {
+		row.bar.SetValue(float64(p.Transferred) / float64(p.Size))
+	} else if p.State == transfer.StateCompleted {
+		row.bar.SetValue(1)
+	}
+}
+
+func (t *transferPanel) trim() {
+	for len(t.order) > maxTransferRows {
+		idx := -1
+		for i, id := range t.order {
+			if t.rows[id].finished {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return
+		}
+		id := t.order[idx]
+		t.box.Remove(t.rows[id].obj)
+		delete(t.rows, id)
+		t.order = append(t.order[:idx], t.order[idx+1:]...)
+	}
+}
+
+func describeTransfer(peer string, p transfer.Progress) string {
+	arrow := "发送给"
+	if p.Direction == transfer.Receiving {
+		arrow = "接收自"
+	}
+	state := map[transfer.State]string{
+		transfer.StateWaiting:   "等待对方接受",
+		transfer.StateActive:    "传输中",
+		transfer.StateVerifying: "校验中",
+		transfer.StateCompleted: "已完成",
+		transfer.StateRejected:  "对方已拒绝",
+		transfer.StateCanceled:  "已取消",
+		transfer.StateFailed:    "失败",
+	}[p.State]
+	text := fmt.Sprintf("%s %s  %s  %s / %s", arrow, peer, p.Name, formatBytes(p.Transferred), formatBytes(p.Size))
+	if p.Resumed > 0 && !p.State.Finished() {
+		text += fmt.Sprintf("（从 %s 处续传）", formatBytes(p.Resumed))
+	}
+	text += "  " + state
+	if p.Err != nil {
+		text += ": " + p.Err.Error()
+	}
+	if p.State == transfer.StateCompleted && p.Direction == transfer.Receiving {
+		text += "，已保存到 " + p.Path
+	}
+	return text
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// downloadDir 返回接收文件的保存目录。固定目录便于断点续传找到之前的未完成部分。
+func downloadDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "snap-screen")
+	}
+	return filepath.Join(home, "Downloads", "snap-screen")
+}
+
+// askAcceptFile 询问是否接收对方的文件
+func askAcceptFile(w fyne.Window, peer string, o transfer.Offer, accept func(dir string) error, reject func() error) {
+	dir := downloadDir()
+	msg := fmt.Sprintf("%s 想发送文件：\n%s（%s）\n\n接收后保存到 %s", peer, o.Name, formatBytes(o.Size), dir)
+	dialog.ShowConfirm("接收文件", msg, func(ok bool) {
+		var err error
+		if ok {
+			err = accept(dir)
+		} else {
+			err = reject()
+		}
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	}, w)
+}
+
+// droppedPaths 过滤拖放进窗口的本地文件
+func droppedPaths(uris []fyne.URI) []string {
+	paths := make([]string, 0, len(uris))
+	for _, u := range uris {
+		if u.Scheme() == "file" {
+			paths = append(paths, u.Path())
+		}
+	}
+	return paths
+}
+
+// pickFile 选择一个要发送的本地文件
+func pickFile(w fyne.Window, onPicked func(path string)) {
+	dialog.ShowFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil || rc == nil {
+			return
+		}
+		path := rc.URI().Path()
+		rc.Close()
+		onPicked(path)
+	}, w)
+}
@@ -0,0 +1,352 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"snap-screen/pkg/client"
+	"snap-screen/pkg/sink"
+)
+
+// view 命令定期输出一次收帧统计
+const viewStatsInterval = 5 * time.Second
+
+// viewOptions 为 view 子命令的参数
+type viewOptions struct {
+	signalURL  string
+	streamID   string
+	sink       string
+	dir        string
+	format     string
+	quality    int
+	segSize    int
+	segTime    time.Duration
+	listen     string
+	maxClients int
+	auth       string
+	frames     int
+	duration   time.Duration
+	timeout    time.Duration
+	reconnect  bool
+	jsonOutput bool
+}
+
+// RunViewCommand 实现 view 子命令：不打开窗口订阅一个 stream，把解码后的帧交给指定的输出，
+// 直到收到 SIGINT / SIGTERM 或达到指定的帧数 / 时长。返回进程退出码。
+func RunViewCommand(args []string) int {
+	var opts viewOptions
+	fs := flag.NewFlagSet("view", flag.ContinueOnError)
+	fs.StringVar(&opts.signalURL, "signal", "", "信令服务器地址，默认 ws://127.0.0.1:8080/ws")
+	fs.StringVar(&opts.streamID, "stream", "", "要观看的 stream ID（必填）")
+	fs.StringVar(&opts.sink, "sink", "files", "输出: files（按序号保存图片）/ mjpeg（MJPEG 流写到标准输出）/ avi（录制为 Motion-JPEG AVI）/ http（在本地 HTTP 端口转发为 MJPEG）/ count（只计数）")
+	fs.StringVar(&opts.dir, "dir", "frames", "-sink files 时图片的保存目录，-sink avi 时录制文件的保存目录")
+	fs.StringVar(&opts.format, "format", sink.FormatJPEG, "-sink files 时的图片格式: jpeg / png")
+	fs.IntVar(&opts.quality, "quality", 0, "需要重新编码 JPEG 时的质量 1~100，默认 85；以 JPEG 传输的帧原样写出")
+	fs.IntVar(&opts.segSize, "segment-size", 0, "-sink avi 时每个文件的大小上限（MB），达到后写下一个文件，0 表示不限（单个文件最大 1 GB）")
+	fs.DurationVar(&opts.segTime, "segment-duration", 0, "-sink avi 时每个文件的时长上限，例如 10m，0 表示不限")
+	fs.StringVar(&opts.listen, "listen", sink.DefaultMJPEGAddr, "-sink http 时的监听地址，供局域网访问时改为 0.0.0.0:8090")
+	fs.IntVar(&opts.maxClients, "max-clients", sink.DefaultMJPEGMaxClients, "-sink http 时同时观看 MJPEG 流的客户端上限")
+	fs.StringVar(&opts.auth, "auth", "", "-sink http 时要求的 HTTP Basic 认证，格式 用户名:密码，留空则不需要认证")
+	fs.IntVar(&opts.frames, "frames", 0, "收到该数量的帧后退出，0 表示不限")
+	fs.DurationVar(&opts.duration, "duration", 0, "观看该时长后退出，例如 30s，0 表示不限")
+	fs.DurationVar(&opts.timeout, "timeout", 15*time.Second, "订阅后超过该时长仍未收到画面则以失败退出，0 表示一直等待")
+	fs.BoolVar(&opts.reconnect, "reconnect", true, "断线或 stream 被移除后自动重连；关闭时连接断开即退出")
+	fs.BoolVar(&opts.jsonOutput, "json", false, "以 JSON Lines 输出状态")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintln(out, "用法: snap-screen view -stream ID [参数]")
+		fmt.Fprintln(out, "\n不打开窗口观看，把收到的画面保存为图片、输出为 MJPEG、录制为 AVI、在本地 HTTP 端口转发或只统计帧率。")
+		fmt.Fprintln(out, "-sink http 时提供 /stream.mjpg（MJPEG 流）和 /snapshot.jpg（最新一帧），可直接用于 OBS、VLC 或网页 <img>。")
+		fmt.Fprintln(out, "状态输出到标准输出；-sink mjpeg 时标准输出留给画面，状态改为输出到标准错误。")
+		fmt.Fprintln(out, "退出码: 0 正常结束，1 超时未收到画面或写出失败，2 参数错误，3 启动失败\n\n参数:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "多余的参数:", strings.Join(fs.Args(), " "))
+		return exitUsage
+	}
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "参数错误:", err)
+		return exitUsage
+	}
+
+	var statusOut io.Writer = os.Stdout
+	if opts.sink == "mjpeg" {
+		statusOut = os.Stderr
+	}
+	out := newStatusPrinter(statusOut, opts.jsonOutput)
+
+	inner, err := opts.newSink(out)
+	if err != nil {
+		out.print(opts.streamID, "error", "错误", err.Error())
+		return exitStartup
+	}
+	vs := newViewSink(inner, opts.frames)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if srv, ok := inner.(*sink.MJPEGServer); ok {
+		defer srv.Close()
+	}
+	rec, recording := inner.(*sink.AVI)
+	cfg := client.ViewerConfig{
+		SignalURL: opts.signalURL,
+		Sink:      vs,
+		Reconnect: client.ReconnectConfig{Disable: !opts.reconnect},
+	}
+	if recording {
+		// 以录制的方式写出，Publisher 会收到本端正在录制的提示
+		cfg.Sink = nil
+	}
+	viewer, err := client.NewViewer(opts.streamID, cfg)
+	if err != nil {
+		out.print(opts.streamID, "error", "错误", "订阅失败: "+err.Error())
+		return exitStartup
+	}
+	defer viewer.Stop()
+	if recording {
+		viewer.StartRecording(vs)
+		defer func() {
+			viewer.StopRecording()
+			// 收尾失败已由 OnSegment 输出
+			_ = rec.Close()
+		}()
+	}
+	events := viewer.Events()
+
+	var timeout, deadline <-chan time.Time
+	if opts.timeout > 0 {
+		timeout = time.After(opts.timeout)
+	}
+	if opts.duration > 0 {
+		deadline = time.After(opts.duration)
+	}
+	ticker := time.NewTicker(viewStatsInterval)
+	defer ticker.Stop()
+	first := vs.first
+
+	code := exitOK
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-first:
+			out.print(opts.streamID, "live", "观看中", "已收到第一帧")
+			first, timeout = nil, nil
+		case <-timeout:
+			out.print(opts.streamID, "error", "错误", fmt.Sprintf("%s 内未收到画面", opts.timeout))
+			code = exitFailure
+			break loop
+		case <-deadline:
+			break loop
+		case <-vs.done:
+			break loop
+		case err := <-vs.errc:
+			out.print(opts.streamID, "error", "错误", "写出画面失败: "+err.Error())
+			code = exitFailure
+			break loop
+		case ev, ok := <-events:
+			if !ok {
+				break loop
+			}
+			printViewerEvent(out, opts.streamID, ev)
+			if ev.Type == client.ViewerEventState && ev.ViewerState == client.ViewerStateEnded {
+				// 关闭重连时会话会自行结束：Publisher 注销 stream 视为正常结束，其他原因为失败
+				if !errors.Is(ev.Err, client.ErrStreamRemoved) || vs.counter.Stats().Frames == 0 {
+					code = exitFailure
+				}
+				break loop
+			}
+		case <-ticker.C:
+			out.print(opts.streamID, "stats", "统计", vs.summary())
+		}
+	}
+	out.print(opts.streamID, "stopped", "已停止", vs.summary())
+	return code
+}
+
+// viewerStateTexts 为连接状态的展示文字
+var viewerStateTexts = map[client.ViewerState]string{
+	client.ViewerStateConnecting:   "连接中",
+	client.ViewerStateConnected:    "已连接",
+	client.ViewerStateStalled:      "画面停滞",
+	client.ViewerStateReconnecting: "重连中",
+	client.ViewerStateEnded:        "已结束",
+}
+
+// printViewerEvent 输出会话事件
+func printViewerEvent(out *statusPrinter, streamID string, ev client.ViewerEvent) {
+	switch ev.Type {
+	case client.ViewerEventState:
+		out.print(streamID, string(ev.ViewerState), viewerStateTexts[ev.ViewerState], ev.Detail)
+	case client.ViewerEventStreamState:
+		if ev.State == client.StreamStatePaused {
+			out.print(streamID, "paused", "对方已暂停", "画面为占位帧")
+		} else {
+			out.print(streamID, "live", "观看中", "对方正在分享")
+		}
+	case client.ViewerEventStreamRemoved:
+		out.print(streamID, "removed", "stream 已移除", ev.Err.Error())
+	case client.ViewerEventError:
+		out.print(streamID, "error", "错误", ev.Err.Error())
+	}
+}
+
+// validate 校验参数
+func (o *viewOptions) validate() error {
+	if o.streamID == "" {
+		return errors.New("必须指定 -stream")
+	}
+	switch o.sink {
+	case "files":
+		if o.format != sink.FormatJPEG && o.format != sink.FormatPNG {
+			return errors.New("-format 只能是 jpeg 或 png")
+		}
+	case "avi":
+		if o.segSize < 0 || o.segTime < 0 {
+			return errors.New("-segment-size / -segment-duration 不能为负数")
+		}
+	case "http":
+		if o.maxClients <= 0 {
+			return errors.New("-max-clients 必须大于 0")
+		}
+		if o.auth != "" && !strings.Contains(o.auth, ":") {
+			return errors.New("-auth 的格式为 用户名:密码")
+		}
+	case "mjpeg", "count":
+	default:
+		return errors.New("未知的输出: " + o.sink)
+	}
+	if o.quality < 0 || o.quality > 100 {
+		return errors.New("-quality 必须是 1~100 的整数")
+	}
+	if o.frames < 0 || o.duration < 0 || o.timeout < 0 {
+		return errors.New("-frames / -duration / -timeout 不能为负数")
+	}
+	return nil
+}
+
+// newSink 按参数创建输出，count 时返回 nil（只由 viewSink 计数）；录制的每个文件写完时、开始转发时输出到 out
+func (o *viewOptions) newSink(out *statusPrinter) (client.FrameSink, error) {
+	switch o.sink {
+	case "files":
+		d, err := sink.NewDir(o.dir, o.format, o.quality)
+		if err != nil {
+			return nil, fmt.Errorf("创建输出目录失败: %w", err)
+		}
+		return d, nil
+	case "mjpeg":
+		return sink.NewMJPEG(os.Stdout, o.quality), nil
+	case "avi":
+		rec, err := sink.NewAVI(o.dir, sink.AVIOptions{
+			Prefix:      recordFilePrefix("view", o.streamID),
+			Quality:     o.quality,
+			MaxSize:     int64(o.segSize) << 20,
+			MaxDuration: o.segTime,
+			OnSegment: func(path string, err error) {
+				if err != nil {
+					out.print(o.streamID, "error", "错误", "录制文件收尾失败: "+err.Error())
+					return
+				}
+				out.print(o.streamID, "saved", "已保存", path)
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("创建录制目录失败: %w", err)
+		}
+		return rec, nil
+	case "http":
+		user, pass, _ := strings.Cut(o.auth, ":")
+		srv, err := sink.NewMJPEGServer(sink.MJPEGServerOptions{
+			Addr:       o.listen,
+			MaxClients: o.maxClients,
+			Username:   user,
+			Password:   pass,
+			Quality:    o.quality,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("开启本地转发失败: %w", err)
+		}
+		out.print(o.streamID, "serving", "转发中", srv.URL())
+		return srv, nil
+	}
+	return nil, nil
+}
+
+// viewSink 包装实际的输出：统计帧数，达到上限后丢弃后续帧，并把首帧、结束和写出错误通知给命令主循环
+type viewSink struct {
+	sink    client.FrameSink
+	limit   int
+	counter *sink.Counter
+
+	first chan struct{}
+	done  chan struct{}
+	errc  chan error
+
+	mu     sync.Mutex
+	n      int
+	failed bool
+}
+
+func newViewSink(inner client.FrameSink, limit int) *viewSink {
+	return &viewSink{
+		sink:    inner,
+		limit:   limit,
+		counter: sink.NewCounter(),
+		first:   make(chan struct{}),
+		done:    make(chan struct{}),
+		errc:    make(chan error, 1),
+	}
+}
+
+// WriteFrame 实现 client.FrameSink
+func (v *viewSink) WriteFrame(f client.Frame) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.failed || (v.limit > 0 && v.n >= v.limit) {
+		return nil
+	}
+	if v.sink != nil {
+		if err := v.sink.WriteFrame(f); err != nil {
+			v.failed = true
+			v.errc <- err
+			return err
+		}
+	}
+	_ = v.counter.WriteFrame(f)
+	v.n++
+	if v.n == 1 {
+		close(v.first)
+	}
+	if v.n == v.limit {
+		close(v.done)
+	}
+	return nil
+}
+
+// summary 返回统计文字
+func (v *viewSink) summary() string {
+	st := v.counter.Stats()
+	if st.Frames == 0 {
+		return "尚未收到画面"
+	}
+	return fmt.Sprintf("已收到 %d 帧（%dx%d，平均 %.1f fps，Publisher 跳过 %d 帧）",
+		st.Frames, st.Width, st.Height, st.FPS(), st.Skipped)
+}
@@ -1,15 +1,23 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"image/color"
 	"log"
 	"strings"
+	"time"
 
+	"snap-screen/pkg/annotate"
 	"snap-screen/pkg/client"
+	"snap-screen/pkg/clipboard"
+	"snap-screen/pkg/sink"
+	"snap-screen/pkg/transfer"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -35,6 +43,23 @@ func RunViewerUI(a fyne.App, w fyne.Window) {
 	streamSelect := widget.NewSelect([]string{}, nil)
 	streamSelect.PlaceHolder = "请选择要订阅的 Stream ID"
 
+	iceOpts := newICEOptions()
+
+	// 默认不接收对方剪贴板，避免内容被意外覆盖
+	clipboardCheck := widget.NewCheck("开启剪贴板共享（接收对方推送的剪贴板）", nil)
+
+	// 回看：在内存中缓冲最近一段画面，观看时可以暂停、往回拖动、保存片段
+	timeShiftSelect := widget.NewSelect(nil, nil)
+	for _, d := range timeShiftDurations {
+		timeShiftSelect.Options = append(timeShiftSelect.Options, d.label)
+	}
+	timeShiftSelect.SetSelected(timeShiftDurations[1].label)
+	timeShiftSizeSelect := widget.NewSelect(nil, nil)
+	for _, s := range timeShiftSizes {
+		timeShiftSizeSelect.Options = append(timeShiftSizeSelect.Options, s.label)
+	}
+	timeShiftSizeSelect.SetSelected(timeShiftSizes[1].label)
+
 	statusLabel := widget.NewLabel("状态: 未连接")
 	statusDetail := widget.NewLabel("")
 
@@ -77,6 +102,9 @@ func RunViewerUI(a fyne.App, w fyne.Window) {
 		}
 	})
 
+	// 已打开的观看窗口，取消订阅时全部关闭
+	viewWindows := map[fyne.Window]struct{}{}
+
 	subBtn := widget.NewButton("订阅", func() {
 		streamID := streamSelect.Selected
 		if streamID == "" {
@@ -86,35 +114,43 @@ func RunViewerUI(a fyne.App, w fyne.Window) {
 		}
 		log.Println("订阅流:", streamID)
 
-		// 为每次订阅单独弹出一个窗口用于显示画面
-		viewWin := a.NewWindow("Viewer - " + streamID)
-		viewWin.Resize(fyne.NewSize(960, 540))
-
-		img := canvas.NewImageFromImage(nil)
-		img.FillMode = canvas.ImageFillContain
-		img.SetMinSize(fyne.NewSize(800, 450))
-
-		// 支持 F11 切换真正全屏
-		viewWin.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
-			if ev.Name == fyne.KeyF11 {
-				viewWin.SetFullScreen(!viewWin.FullScreen())
+		// 为每次订阅单独弹出一个窗口用于显示画面，可同时订阅多路
+		base := client.ViewerConfig{SignalURL: strings.TrimSpace(signalEntry.Text), ICE: iceOpts.config()}
+		opts := viewerWindowOptions{shareClipboard: clipboardCheck.Checked}
+		for _, d := range timeShiftDurations {
+			if d.label == timeShiftSelect.Selected {
+				opts.timeShift = d.duration
 			}
-		})
-
-		viewWin.SetContent(img)
-		viewWin.Show()
-
-		if err := client.StartViewer(streamID, img, strings.TrimSpace(signalEntry.Text)); err != nil {
+		}
+		for _, s := range timeShiftSizes {
+			if s.label == timeShiftSizeSelect.Selected {
+				opts.timeShiftSize = s.size
+			}
+		}
+		var viewWin fyne.Window
+		viewWin, err := openViewerWindow(a, streamID, base, opts, func() { delete(viewWindows, viewWin) })
+		if err != nil {
 			statusLabel.SetText("状态: 错误")
 			statusDetail.SetText("订阅失败: " + err.Error())
 			return
 		}
+		viewWindows[viewWin] = struct{}{}
 		statusLabel.SetText("状态: 已订阅")
-		statusDetail.SetText("正在接收远程画面")
+		statusDetail.SetText(fmt.Sprintf("正在接收远程画面，共 %d 路", len(viewWindows)))
+	})
+	// 监控墙：勾选多路 stream 在一个窗口中按网格同时观看
+	wallBtn := widget.NewButton("监控墙…", func() {
+		chooseWallStreams(w, streamSelect.Options, func(ids []string) {
+			log.Println("打开监控墙:", ids)
+			base := client.ViewerConfig{SignalURL: strings.TrimSpace(signalEntry.Text), ICE: iceOpts.config()}
+			openWallWindow(a, ids, base)
+		})
 	})
 	unsubBtn := widget.NewButton("取消订阅", func() {
 		log.Println("取消订阅")
-		client.StopViewer()
+		for viewWin := range viewWindows {
+			viewWin.Close()
+		}
 		statusLabel.SetText("状态: 已取消")
 		statusDetail.SetText("已取消订阅")
 	})
@@ -126,10 +162,335 @@ func RunViewerUI(a fyne.App, w fyne.Window) {
 		widget.NewLabel("信令服务器"),
 		signalEntry,
 		container.NewGridWithColumns(2, streamSelect, refreshBtn),
+		iceOpts.form(),
+		clipboardCheck,
+		container.NewGridWithColumns(2, timeShiftSelect, timeShiftSizeSelect),
 		statusLabel,
 		statusDetail,
 		subBtn,
+		wallBtn,
 		unsubBtn,
 	)
 	w.SetContent(content)
 }
+
+// viewerWindowOptions 为主界面上对新打开的观看窗口生效的选项
+type viewerWindowOptions struct {
+	shareClipboard bool
+	// timeShift / timeShiftSize 为回看缓冲的时长和内存上限，timeShift 为 0 时不开启回看
+	timeShift     time.Duration
+	timeShiftSize int64
+}
+
+// openViewerWindow 为 streamID 打开一个观看窗口。每个窗口有自己的 Viewer 会话，
+// 可以同时观看多路；关闭窗口即停止观看并调用 onClosed。base 提供信令服务器和 ICE 配置。
+func openViewerWindow(a fyne.App, streamID string, base client.ViewerConfig, opts viewerWindowOptions, onClosed func()) (fyne.Window, error) {
+	// 按钮和回调只会在会话创建之后触发
+	var viewer *client.Viewer
+	viewWin := a.NewWindow("Viewer - " + streamID)
+	viewWin.Resize(fyne.NewSize(960, 540))
+
+	img := canvas.NewImageFromImage(nil)
+	img.FillMode = canvas.ImageFillContain
+	img.SetMinSize(fyne.NewSize(800, 450))
+
+	// 支持 F11 切换真正全屏
+	viewWin.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		if ev.Name == fyne.KeyF11 {
+			viewWin.SetFullScreen(!viewWin.FullScreen())
+		}
+	})
+
+	// Publisher 暂停分享时在画面中央给出提示
+	pausedBadge := widget.NewLabelWithStyle("对方已暂停分享", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	pausedBadge.Hide()
+
+	// 连接状态：未连上、画面停滞或重连中时在画面上盖一层提示，避免停在最后一帧让人误以为画面正常
+	stateTitle := canvas.NewText(viewerStateTexts[client.ViewerStateConnecting], color.White)
+	stateTitle.TextStyle = fyne.TextStyle{Bold: true}
+	stateTitle.TextSize = 20
+	stateTitle.Alignment = fyne.TextAlignCenter
+	stateDetail := canvas.NewText("", color.White)
+	stateDetail.Alignment = fyne.TextAlignCenter
+	stateOverlay := container.NewCenter(container.NewStack(
+		canvas.NewRectangle(color.NRGBA{A: 180}),
+		container.NewPadded(container.NewVBox(stateTitle, stateDetail))))
+
+	// 右上角显示 Publisher 测得的端到端延迟
+	latencyLabel := widget.NewLabel("")
+
+	// 远程控制：勾选后向 Publisher 申请，对方同意后画面上的键鼠操作会转发过去
+	board := annotate.NewBoard()
+	view := newRemoteView(img, board, viewWin)
+	remoteStatus := widget.NewLabel("")
+
+	// 录制：把收到的画面写成 Motion-JPEG AVI，开始时 Publisher 会收到提示
+	recordCtl := newRecordControl(recordFilePrefix("view", streamID),
+		func(rec client.FrameSink) { viewer.StartRecording(rec) },
+		func() client.FrameSink { return viewer.StopRecording() },
+		remoteStatus.SetText)
+	latencyCorner := container.NewVBox(container.NewHBox(layout.NewSpacer(), recordCtl.indicator, latencyLabel))
+	remoteCheck := widget.NewCheck("申请远程控制", func(on bool) {
+		if err := viewer.RequestRemoteControl(on); err != nil {
+			remoteStatus.SetText(err.Error())
+			return
+		}
+		if on {
+			remoteStatus.SetText("等待对方同意…")
+		} else {
+			view.setEnabled(false)
+			remoteStatus.SetText("")
+		}
+	})
+
+	// 标注：选中工具后在画面上拖动绘制，所有 Viewer 都能看到，可设置 10 秒后自动淡出
+	annotationTools := map[string]annotate.Kind{
+		"标注: 关闭": "",
+		"画笔":     annotate.Stroke,
+		"箭头":     annotate.Arrow,
+		"矩形":     annotate.Rect,
+		"文字":     annotate.Text,
+	}
+	annotationColors := map[string]string{
+		"红": "#e53935",
+		"黄": "#fdd835",
+		"绿": "#43a047",
+		"蓝": "#1e88e5",
+	}
+	toolSelect := widget.NewSelect([]string{"标注: 关闭", "画笔", "箭头", "矩形", "文字"}, nil)
+	colorSelect := widget.NewSelect([]string{"红", "黄", "绿", "蓝"}, nil)
+	fadeCheck := widget.NewCheck("10 秒后淡出", nil)
+	applyTool := func(string) {
+		var ttl time.Duration
+		if fadeCheck.Checked {
+			ttl = 10 * time.Second
+		}
+		view.setTool(annotationTools[toolSelect.Selected], annotationColors[colorSelect.Selected], ttl)
+	}
+	toolSelect.OnChanged = applyTool
+	colorSelect.OnChanged = applyTool
+	fadeCheck.OnChanged = func(bool) { applyTool("") }
+	toolSelect.SetSelected("标注: 关闭")
+	colorSelect.SetSelected("红")
+	fadeCheck.SetChecked(true)
+	undoBtn := widget.NewButton("撤销我的标注", func() {
+		if err := viewer.UndoAnnotation(); err != nil {
+			remoteStatus.SetText(err.Error())
+		}
+	})
+	clearBtn := widget.NewButton("清除全部标注", func() {
+		if err := viewer.ClearAnnotations(); err != nil {
+			remoteStatus.SetText(err.Error())
+		}
+	})
+
+	// 剪贴板回传：Publisher 允许后才可用
+	sendClipboard := func(c clipboard.Content) {
+		if err := viewer.SendClipboard(c); err != nil {
+			remoteStatus.SetText("发送剪贴板失败: " + err.Error())
+			return
+		}
+		remoteStatus.SetText("剪贴板已发送")
+		notifyClipboard("剪贴板已发送给对方", c)
+	}
+	sendClipboardBtn := widget.NewButton("发送剪贴板", func() {
+		c, err := newFyneClipboard(viewWin.Clipboard()).Read()
+		if err != nil {
+			remoteStatus.SetText("读取剪贴板失败: " + err.Error())
+			return
+		}
+		sendClipboard(c)
+	})
+	sendImageBtn := widget.NewButton("发送图片…", func() {
+		pickClipboardImage(viewWin, clipboard.Limits{}, sendClipboard)
+	})
+	sendClipboardBtn.Disable()
+	sendImageBtn.Disable()
+
+	// 文件：拖进观看窗口或点击按钮发送给 Publisher
+	transfers := newTransferPanel()
+	sendFiles := func(paths []string) {
+		for _, path := range paths {
+			if _, err := viewer.SendFile(path); err != nil {
+				remoteStatus.SetText("发送文件失败: " + err.Error())
+			}
+		}
+	}
+	sendFileBtn := widget.NewButton("发送文件…", func() {
+		pickFile(viewWin, func(path string) { sendFiles([]string{path}) })
+	})
+	viewWin.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		sendFiles(droppedPaths(uris))
+	})
+
+	// 放大：滚轮或按钮放大、左键拖动平移，对方按原始分辨率只为本端发送该区域
+	zoomLabel := widget.NewLabel("1.0×")
+	zoomInBtn := widget.NewButton("放大", func() { view.zoomBy(2, nil) })
+	zoomOutBtn := widget.NewButton("缩小", func() { view.zoomBy(0.5, nil) })
+	zoomResetBtn := widget.NewButton("完整画面", view.resetZoom)
+
+	// 截图：请求对方按原始分辨率重新采集一张 PNG，不受推流缩放和有损编码影响
+	var snapshotBtn *widget.Button
+	snapshotBtn = widget.NewButton("原始分辨率截图", func() {
+		if err := viewer.RequestSnapshot(); err != nil {
+			remoteStatus.SetText("请求截图失败: " + err.Error())
+			return
+		}
+		snapshotBtn.Disable()
+		remoteStatus.SetText("正在等待对方截图…")
+	})
+
+	// 回看：暂停或往回拖动时画面停在缓冲中的某一帧，后台照常接收
+	var display client.FrameSink = canvasSink{img: img}
+	var shift *timeShift
+	if opts.timeShift > 0 {
+		shift = newTimeShift(sink.RingOptions{MaxAge: opts.timeShift, MaxBytes: opts.timeShiftSize},
+			display, recordFilePrefix("view", streamID), remoteStatus.SetText)
+		display = shift
+	}
+	// 本地转发：在本地 HTTP 端口上把收到的画面转发为 MJPEG，转发的始终是直播画面
+	rebroadcast := newRebroadcastControl(viewWin, display, remoteStatus.SetText)
+
+	toolbar := container.NewVBox(
+		container.NewHBox(remoteCheck, widget.NewSeparator(),
+			toolSelect, colorSelect, fadeCheck, undoBtn, clearBtn, widget.NewSeparator(),
+			rebroadcast.controls()),
+		container.NewHBox(zoomOutBtn, zoomLabel, zoomInBtn, zoomResetBtn, widget.NewSeparator(),
+			sendClipboardBtn, sendImageBtn, sendFileBtn, snapshotBtn, widget.NewSeparator(),
+			recordCtl.controls(), remoteStatus))
+	if shift != nil {
+		toolbar.Add(shift.controls())
+	}
+
+	viewWin.SetContent(container.NewBorder(toolbar, transfers.box, nil, nil,
+		container.NewStack(view, container.NewCenter(pausedBadge), stateOverlay, latencyCorner)))
+
+	cfg := client.ViewerConfig{
+		SignalURL: base.SignalURL,
+		ICE:       base.ICE,
+		OnLatency: func(d time.Duration) {
+			latencyLabel.SetText(fmt.Sprintf("延迟 %.0fms", float64(d)/float64(time.Millisecond)))
+		},
+		OnRemoteControl: func(enabled bool, reason string) {
+			view.setEnabled(enabled)
+			if enabled {
+				remoteStatus.SetText("已获得控制权，点击画面后可操作对方桌面")
+				return
+			}
+			remoteCheck.SetChecked(false)
+			remoteStatus.SetText(reason)
+		},
+		Annotations: board,
+		OnFileOffer: func(o transfer.Offer) {
+			askAcceptFile(viewWin, "Publisher", o,
+				func(dir string) error { return viewer.AcceptFile(o.ID, dir) },
+				func() error { return viewer.RejectFile(o.ID) })
+		},
+		OnTransfer: func(p transfer.Progress) {
+			transfers.update("Publisher", p)
+		},
+		OnZoom: func(r client.ZoomRegion) {
+			view.setShownZoom(r)
+			zoomLabel.SetText(fmt.Sprintf("%.1f×", r.Factor()))
+		},
+		OnSnapshot: func(path string, err error) {
+			snapshotBtn.Enable()
+			if err != nil {
+				remoteStatus.SetText("截图失败: " + err.Error())
+				return
+			}
+			remoteStatus.SetText("截图已保存到 " + path)
+			showSnapshot(viewWin, path)
+		},
+		OnClipboard: func(c clipboard.Content, err error) {
+			switch {
+			case errors.Is(err, clipboard.ErrImageUnsupported):
+				notifyClipboard("收到对方推送的图片", c)
+				showClipboardImage(viewWin, "对方推送的图片", c)
+			case err != nil:
+				remoteStatus.SetText("拒绝对方推送的剪贴板: " + err.Error())
+			default:
+				notifyClipboard("剪贴板已更新（来自 Publisher）", c)
+				remoteStatus.SetText("已收到对方的剪贴板: " + describeClipboard(c))
+			}
+		},
+		OnClipboardPolicy: func(upload bool) {
+			if upload {
+				sendClipboardBtn.Enable()
+				sendImageBtn.Enable()
+			} else {
+				sendClipboardBtn.Disable()
+				sendImageBtn.Disable()
+			}
+		},
+		OnAnnotationsCleared: func(by string) {
+			if by == "" {
+				remoteStatus.SetText("对方已清除全部标注")
+			} else {
+				remoteStatus.SetText("全部标注已被 " + by + " 清除")
+			}
+		},
+	}
+	if opts.shareClipboard {
+		cfg.Clipboard = newFyneClipboard(viewWin.Clipboard())
+	}
+	cfg.Sink = rebroadcast
+	viewer, err := client.NewViewer(streamID, cfg)
+	if err != nil {
+		if shift != nil {
+			shift.close()
+		}
+		viewWin.Close()
+		return nil, err
+	}
+	view.viewer = viewer
+	watchViewerEvents(viewer, func(ev client.ViewerEvent) {
+		switch ev.Type {
+		case client.ViewerEventStreamState:
+			if ev.State == client.StreamStatePaused {
+				pausedBadge.Show()
+			} else {
+				pausedBadge.Hide()
+			}
+		case client.ViewerEventState:
+			if ev.ViewerState == client.ViewerStateConnected {
+				stateOverlay.Hide()
+				return
+			}
+			stateTitle.Text = viewerStateTexts[ev.ViewerState]
+			stateDetail.Text = ev.Detail
+			stateTitle.Refresh()
+			stateDetail.Refresh()
+			stateOverlay.Show()
+		case client.ViewerEventError:
+			remoteStatus.SetText("错误: " + ev.Err.Error())
+		}
+	})
+
+	stopAnnotations := make(chan struct{})
+	viewWin.SetOnClosed(func() {
+		recordCtl.finish()
+		viewer.Stop()
+		rebroadcast.finish()
+		if shift != nil {
+			shift.close()
+		}
+		close(stopAnnotations)
+		if onClosed != nil {
+			onClosed()
+		}
+	})
+	go view.watchAnnotations(stopAnnotations)
+	viewWin.Show()
+	return viewWin, nil
+}
+
+// watchViewerEvents 在后台把 v 的事件逐个交给 handle，会话结束、通道关闭后退出。
+// 窗口必须读取 Events，否则缓冲满后每个新事件都会被丢弃
+func watchViewerEvents(v *client.Viewer, handle func(client.ViewerEvent)) {
+	go func() {
+		for ev := range v.Events() {
+			handle(ev)
+		}
+	}()
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+
+	"snap-screen/pkg/client"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 监控墙：在一个窗口里按网格同时观看多路 stream，每路一个独立的 Viewer 会话。
+// 任意一路可以放大到整个窗口并进入全屏，Esc 返回网格。
+
+// wallColumnOptions 为列数选项，"自动" 按路数取接近正方形的排布
+var wallColumnOptions = []string{"自动", "1", "2", "3", "4"}
+
+// wallTile 为监控墙中的一路画面
+type wallTile struct {
+	streamID string
+	viewer   *client.Viewer
+	img      *canvas.Image
+	status   *widget.Label
+
+	mu     sync.Mutex
+	state  string
+	paused bool
+}
+
+// updateStatus 刷新名称旁的状态文字
+func (t *wallTile) updateStatus() {
+	t.mu.Lock()
+	text := t.state
+	if t.paused && text == viewerStateTexts[client.ViewerStateConnected] {
+		text = "对方已暂停"
+	}
+	t.mu.Unlock()
+	t.status.SetText(text)
+}
+
+// handleEvent 按 Viewer 事件更新状态文字
+func (t *wallTile) handleEvent(ev client.ViewerEvent) {
+	t.mu.Lock()
+	switch ev.Type {
+	case client.ViewerEventState:
+		t.state = viewerStateTexts[ev.ViewerState]
+		if ev.ViewerState != client.ViewerStateConnected && ev.Detail != "" {
+			t.state += ": " + ev.Detail
+		}
+	case client.ViewerEventStreamState:
+		t.paused = ev.State == client.StreamStatePaused
+	case client.ViewerEventError:
+		log.Println("wall viewer error:", t.streamID, ev.Err)
+	}
+	t.mu.Unlock()
+	t.updateStatus()
+}
+
+// chooseWallStreams 让用户从 streamIDs 中勾选要放到监控墙上的 stream
+func chooseWallStreams(parent fyne.Window, streamIDs []string, onChosen func([]string)) {
+	if len(streamIDs) == 0 {
+		dialog.ShowError(errors.New("当前没有可用的流，请先刷新列表"), parent)
+		return
+	}
+	group := widget.NewCheckGroup(streamIDs, nil)
+	group.SetSelected(streamIDs)
+	dialog.ShowCustomConfirm("监控墙", "打开", "取消", container.NewVScroll(group), func(ok bool) {
+		if ok && len(group.Selected) > 0 {
+			onChosen(group.Selected)
+		}
+	}, parent)
+}
+
+// openWallWindow 为 streamIDs 打开监控墙窗口，关闭窗口即停止全部会话。
+// base 提供信令服务器和 ICE 配置；某一路启动失败只在该格中提示，不影响其他路。
+func openWallWindow(a fyne.App, streamIDs []string, base client.ViewerConfig) {
+	win := a.NewWindow("监控墙 - " + strconv.Itoa(len(streamIDs)) + " 路")
+	win.Resize(fyne.NewSize(1280, 720))
+
+	tiles := make([]*wallTile, 0, len(streamIDs))
+	for _, id := range streamIDs {
+		t := &wallTile{streamID: id, status: widget.NewLabel(viewerStateTexts[client.ViewerStateConnecting])}
+		t.img = canvas.NewImageFromImage(nil)
+		t.img.FillMode = canvas.ImageFillContain
+		t.img.SetMinSize(fyne.NewSize(160, 90))
+
+		cfg := base
+		cfg.Sink = canvasSink{img: t.img}
+		viewer, err := client.NewViewer(id, cfg)
+		if err != nil {
+			log.Println("wall viewer error:", id, err)
+			t.status.SetText("订阅失败: " + err.Error())
+		} else {
+			watchViewerEvents(viewer, t.handleEvent)
+		}
+		t.viewer = viewer
+		tiles = append(tiles, t)
+	}
+
+	columns := widget.NewSelect(wallColumnOptions, nil)
+	var promoted *wallTile
+	var showGrid func()
+
+	// promote 把一路画面放大到整个窗口并进入全屏
+	promote := func(t *wallTile) {
+		promoted = t
+		backBtn := widget.NewButton("返回监控墙", showGrid)
+		name := widget.NewLabelWithStyle(t.streamID, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		win.SetContent(container.NewBorder(
+			container.NewHBox(backBtn, name, layout.NewSpacer(), t.status), nil, nil, nil, t.img))
+		win.SetFullScreen(true)
+	}
+
+	// showGrid 按当前列数重新排布全部画面。每次都新建容器，
+	// 避免同一个画面对象同时挂在旧的网格和放大视图中。
+	showGrid = func() {
+		if promoted != nil {
+			promoted = nil
+			win.SetFullScreen(false)
+		}
+		cols, err := strconv.Atoi(columns.Selected)
+		if err != nil || cols <= 0 {
+			cols = int(math.Ceil(math.Sqrt(float64(len(tiles)))))
+		}
+		cells := make([]fyne.CanvasObject, 0, len(tiles))
+		for _, t := range tiles {
+			name := widget.NewLabelWithStyle(t.streamID, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			fullBtn := widget.NewButton("全屏", func() { promote(t) })
+			cells = append(cells, container.NewBorder(nil,
+				container.NewHBox(name, t.status, layout.NewSpacer(), fullBtn), nil, nil, t.img))
+		}
+		toolbar := container.NewHBox(widget.NewLabel("列数"), columns)
+		win.SetContent(container.NewBorder(toolbar, nil, nil, nil,
+			container.NewGridWithColumns(max(cols, 1), cells...)))
+	}
+	columns.OnChanged = func(string) { showGrid() }
+	columns.SetSelected(wallColumnOptions[0])
+
+	win.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		switch ev.Name {
+		case fyne.KeyEscape:
+			if promoted != nil {
+				showGrid()
+			}
+		case fyne.KeyF11:
+			win.SetFullScreen(!win.FullScreen())
+		}
+	})
+	win.SetOnClosed(func() {
+		for _, t := range tiles {
+			if t.viewer != nil {
+				t.viewer.Stop()
+			}
+		}
+	})
+	win.Show()
+}
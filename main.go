@@ -1,9 +1,20 @@
 package main
 
 import (
+	"os"
+
 	"snap-screen/internal/app"
 )
 
 func main() {
+	// 带子命令时以命令行模式运行，不打开窗口
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "publish":
+			os.Exit(app.RunPublishCommand(os.Args[2:]))
+		case "view":
+			os.Exit(app.RunViewCommand(os.Args[2:]))
+		}
+	}
 	app.RunMainGUI()
 }
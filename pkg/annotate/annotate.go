@@ -0,0 +1,284 @@
+// Package annotate 实现 Viewer 在共享画面上协作标注：标注的数据结构、
+// 按作者撤销的标注板以及把标注绘制到图像上的渲染器
+package annotate
+
+import (
+	"errors"
+	"image/color"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind 为标注类型
+type Kind string
+
+const (
+	Stroke Kind = "stroke" // 自由画笔
+	Arrow  Kind = "arrow"  // 箭头，Points[0] 为起点、Points[1] 为箭头所指
+	Rect   Kind = "rect"   // 矩形框，Points 为两个对角
+	Text   Kind = "text"   // 文字，Points[0] 为左上角
+)
+
+// 标注的取值范围
+const (
+	DefaultWidth = 0.005 // 默认线宽，占画面高度的比例
+	MaxPoints    = 2000  // 单条画笔的最多采样点数
+	MaxTextLen   = 200   // 文字标注的最多字符数
+	// FadeDuration 为标注到期前逐渐淡出的时长，TTL 较短时取其三分之一
+	FadeDuration = time.Second
+	// maxAnnotations 为标注板最多保留的标注数，超出时丢弃最早的
+	maxAnnotations = 500
+)
+
+// Point 为相对画面的归一化坐标（0~1），与分辨率、窗口大小无关
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Annotation 是一条标注。ID 与 Author 由 Publisher 分配，Viewer 发送时留空即可。
+type Annotation struct {
+	ID     uint64  `json:"id,omitempty"`
+	Author string  `json:"author,omitempty"`
+	Kind   Kind    `json:"kind"`
+	Points []Point `json:"points"`
+	Text   string  `json:"text,omitempty"`
+	// Color 为 "#rrggbb" 形式的颜色，无法解析时使用红色
+	Color string `json:"color,omitempty"`
+	// Width 为线宽占画面高度的比例，文字行高为线宽的 8 倍
+	Width float64 `json:"width,omitempty"`
+	// TTL 为显示时长，到期前 FadeDuration 内逐渐淡出；0 表示一直保留到被撤销或清除
+	TTL time.Duration `json:"ttl,omitempty"`
+	// Age 只在传输时使用，表示发送时标注已经存在的时长，便于新加入的 Viewer 对齐淡出进度
+	Age time.Duration `json:"age,omitempty"`
+
+	created time.Time
+}
+
+// Normalize 检查标注是否合法，并把坐标、线宽裁剪到有效范围
+func (a *Annotation) Normalize() error {
+	want := 0
+	switch a.Kind {
+	case Stroke:
+		if len(a.Points) == 0 || len(a.Points) > MaxPoints {
+			return errors.New("画笔采样点数量无效")
+		}
+	case Arrow, Rect:
+		want = 2
+	case Text:
+		want = 1
+		a.Text = strings.TrimSpace(a.Text)
+		if a.Text == "" {
+			return errors.New("文字标注不能为空")
+		}
+		if utf8.RuneCountInString(a.Text) > MaxTextLen {
+			return errors.New("文字标注过长")
+		}
+	default:
+		return errors.New("未知的标注类型: " + string(a.Kind))
+	}
+	if want > 0 && len(a.Points) != want {
+		return errors.New("标注坐标数量无效")
+	}
+	for i := range a.Points {
+		a.Points[i].X = clamp01(a.Points[i].X)
+		a.Points[i].Y = clamp01(a.Points[i].Y)
+	}
+	if a.Width <= 0 {
+		a.Width = DefaultWidth
+	}
+	a.Width = min(max(a.Width, 0.001), 0.05)
+	if a.TTL < 0 {
+		a.TTL = 0
+	}
+	if a.Age < 0 {
+		a.Age = 0
+	}
+	return nil
+}
+
+// Opacity 返回标注在 now 时刻的不透明度，已过期时为 0
+func (a *Annotation) Opacity(now time.Time) float64 {
+	if a.TTL <= 0 {
+		return 1
+	}
+	remaining := a.TTL - now.Sub(a.created)
+	if remaining <= 0 {
+		return 0
+	}
+	fade := min(FadeDuration, a.TTL/3)
+	if remaining >= fade {
+		return 1
+	}
+	return float64(remaining) / float64(fade)
+}
+
+func (a *Annotation) expired(now time.Time) bool {
+	return a.TTL > 0 && now.Sub(a.created) >= a.TTL
+}
+
+// RGBA 解析 Color，无法解析时返回红色
+func (a *Annotation) RGBA() color.NRGBA {
+	c, err := ParseColor(a.Color)
+	if err != nil {
+		return color.NRGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff}
+	}
+	return c
+}
+
+// ParseColor 解析 "#rrggbb" 形式的颜色
+func ParseColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 {
+		return color.NRGBA{}, errors.New("颜色格式应为 #rrggbb")
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, errors.New("颜色格式应为 #rrggbb")
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+func clamp01(v float64) float64 {
+	return min(max(v, 0), 1)
+}
+
+// Board 保存当前有效的标注，并发安全。
+// Publisher 用它分配 ID 并作为权威副本，Viewer 用它镜像 Publisher 转发的结果。
+type Board struct {
+	mu      sync.Mutex
+	items   []Annotation
+	nextID  uint64
+	version uint64
+}
+
+// NewBoard 创建空的标注板
+func NewBoard() *Board {
+	return &Board{}
+}
+
+// Add 加入一条标注并返回实际保存的副本：ID 为 0 时分配新 ID，
+// 已存在相同 ID 时替换；a.Age 用于推算标注的创建时间
+func (b *Board) Add(a Annotation, now time.Time) Annotation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if a.ID == 0 {
+		b.nextID++
+		a.ID = b.nextID
+	} else if a.ID > b.nextID {
+		b.nextID = a.ID
+	}
+	a.created = now.Add(-a.Age)
+	a.Age = 0
+	a.Points = append([]Point(nil), a.Points...)
+
+	b.version++
+	for i := range b.items {
+		if b.items[i].ID == a.ID {
+			b.items[i] = a
+			return a
+		}
+	}
+	b.items = append(b.items, a)
+	if n := len(b.items) - maxAnnotations; n > 0 {
+		b.items = append(b.items[:0], b.items[n:]...)
+	}
+	return a
+}
+
+// Undo 删除 author 最近的一条标注，返回被删除的标注
+func (b *Board) Undo(author string) (Annotation, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.items) - 1; i >= 0; i-- {
+		if b.items[i].Author == author {
+			a := b.items[i]
+			b.items = append(b.items[:i], b.items[i+1:]...)
+			b.version++
+			return a, true
+		}
+	}
+	return Annotation{}, false
+}
+
+// Remove 按 ID 删除一条标注
+func (b *Board) Remove(id uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.items {
+		if b.items[i].ID == id {
+			b.items = append(b.items[:i], b.items[i+1:]...)
+			b.version++
+			return true
+		}
+	}
+	return false
+}
+
+// Clear 清除全部标注，返回清除的数量
+func (b *Board) Clear() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.items)
+	b.items = nil
+	b.version++
+	return n
+}
+
+// Snapshot 先丢弃已过期的标注，再返回其余标注的副本，Age 为截至 now 的存在时长
+func (b *Board) Snapshot(now time.Time) []Annotation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prune(now)
+	out := make([]Annotation, len(b.items))
+	for i, a := range b.items {
+		a.Age = now.Sub(a.created)
+		out[i] = a
+	}
+	return out
+}
+
+// Len 返回未过期的标注数量
+func (b *Board) Len(now time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prune(now)
+	return len(b.items)
+}
+
+// Version 在每次增删后递增，供界面判断是否需要重绘
+func (b *Board) Version() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.version
+}
+
+// Fading 返回是否存在设置了 TTL 的标注，这类标注在淡出期间需要持续重绘
+func (b *Board) Fading(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prune(now)
+	for i := range b.items {
+		if b.items[i].TTL > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Board) prune(now time.Time) {
+	kept := b.items[:0]
+	for _, a := range b.items {
+		if !a.expired(now) {
+			kept = append(kept, a)
+		}
+	}
+	if len(kept) != len(b.items) {
+		clear(b.items[len(kept):])
+		b.items = kept
+		b.version++
+	}
+}
@@ -0,0 +1,211 @@
+package annotate
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Renderer 把标注绘制到图像上，内部按像素大小缓存字体，可并发使用
+type Renderer struct {
+	font *opentype.Font
+
+	mu    sync.Mutex
+	faces map[int]font.Face
+}
+
+// NewRenderer 创建标注渲染器
+func NewRenderer() (*Renderer, error) {
+	f, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{font: f, faces: make(map[int]font.Face)}, nil
+}
+
+// Render 把标注按顺序绘制到 dst 上。area 为画面在 dst 中占据的区域，
+// 归一化坐标按 area 换算；Viewer 窗口留白时 area 小于 dst。
+func (r *Renderer) Render(dst *image.RGBA, area image.Rectangle, anns []Annotation, now time.Time) {
+	if r == nil || dst == nil || area.Empty() {
+		return
+	}
+	for i := range anns {
+		a := &anns[i]
+		opacity := a.Opacity(now)
+		if opacity <= 0 {
+			continue
+		}
+		c := a.RGBA()
+		c.A = uint8(float64(c.A) * opacity)
+		lw := max(1, int(math.Round(a.Width*float64(area.Dy()))))
+
+		if a.Kind == Text {
+			r.drawText(dst, area, a, c, lw)
+			continue
+		}
+		pts := make([]image.Point, len(a.Points))
+		for j, p := range a.Points {
+			pts[j] = toPixel(area, p)
+		}
+		// 蒙版只覆盖标注所在区域（留出箭头两翼的余量），避免每条标注都分配整帧大小的蒙版
+		pad := lw*4 + 10
+		m := newBrush(bbox(pts).Inset(-pad).Intersect(dst.Bounds()).Intersect(area), lw)
+		if m.mask.Rect.Empty() {
+			continue
+		}
+		switch a.Kind {
+		case Stroke:
+			m.polyline(pts)
+		case Arrow:
+			m.arrow(pts[0], pts[1])
+		case Rect:
+			p, q := pts[0], pts[1]
+			m.polyline([]image.Point{p, {q.X, p.Y}, q, {p.X, q.Y}, p})
+		}
+		m.paint(dst, c)
+	}
+}
+
+func toPixel(area image.Rectangle, p Point) image.Point {
+	return image.Point{
+		X: area.Min.X + int(math.Round(p.X*float64(area.Dx()-1))),
+		Y: area.Min.Y + int(math.Round(p.Y*float64(area.Dy()-1))),
+	}
+}
+
+func bbox(pts []image.Point) image.Rectangle {
+	rect := image.Rectangle{Min: pts[0], Max: pts[0].Add(image.Point{1, 1})}
+	for _, p := range pts[1:] {
+		rect = rect.Union(image.Rectangle{Min: p, Max: p.Add(image.Point{1, 1})})
+	}
+	return rect
+}
+
+func (r *Renderer) face(px int) (font.Face, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.faces[px]; ok {
+		return f, nil
+	}
+	f, err := opentype.NewFace(r.font, &opentype.FaceOptions{Size: float64(px), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	r.faces[px] = f
+	return f, nil
+}
+
+// drawText 以 Points[0] 为左上角绘制文字，带 1 像素的深色阴影保证在任意背景上可读
+func (r *Renderer) drawText(dst *image.RGBA, area image.Rectangle, a *Annotation, c color.NRGBA, lw int) {
+	// 字体对象不支持并发绘制，整段加锁
+	f, err := r.face(max(10, lw*8))
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	at := toPixel(area, a.Points[0])
+	base := fixed.P(at.X, at.Y+f.Metrics().Ascent.Ceil())
+	shadow := color.NRGBA{A: c.A / 2}
+	for _, pass := range []struct {
+		src    color.Color
+		offset fixed.Point26_6
+	}{
+		{shadow, fixed.P(1, 1)},
+		{c, fixed.Point26_6{}},
+	} {
+		d := &font.Drawer{Dst: dst, Src: image.NewUniform(pass.src), Face: f, Dot: base.Add(pass.offset)}
+		d.DrawString(a.Text)
+	}
+}
+
+// brush 在 Alpha 蒙版上用圆形笔触描线，最后一次性以指定颜色合成，
+// 这样线段交叠处不会因为重复绘制而变深
+type brush struct {
+	mask   *image.Alpha
+	radius int
+	disc   []int // disc[dy+radius] 为该行的半宽
+}
+
+func newBrush(clip image.Rectangle, width int) *brush {
+	r := max(1, width/2)
+	disc := make([]int, 2*r+1)
+	for dy := -r; dy <= r; dy++ {
+		disc[dy+r] = int(math.Sqrt(float64(r*r - dy*dy)))
+	}
+	return &brush{mask: image.NewAlpha(clip), radius: r, disc: disc}
+}
+
+func (b *brush) stamp(p image.Point) {
+	bounds := b.mask.Rect
+	for dy := -b.radius; dy <= b.radius; dy++ {
+		y := p.Y + dy
+		if y < bounds.Min.Y || y >= bounds.Max.Y {
+			continue
+		}
+		half := b.disc[dy+b.radius]
+		x0, x1 := max(p.X-half, bounds.Min.X), min(p.X+half+1, bounds.Max.X)
+		if x0 >= x1 {
+			continue
+		}
+		row := b.mask.PixOffset(x0, y)
+		for i := range x1 - x0 {
+			b.mask.Pix[row+i] = 0xff
+		}
+	}
+}
+
+func (b *brush) line(p, q image.Point) {
+	dx, dy := float64(q.X-p.X), float64(q.Y-p.Y)
+	dist := math.Hypot(dx, dy)
+	step := max(1, float64(b.radius)/2)
+	n := int(math.Ceil(dist / step))
+	for i := 0; i <= n; i++ {
+		t := 0.0
+		if n > 0 {
+			t = float64(i) / float64(n)
+		}
+		b.stamp(image.Point{X: p.X + int(math.Round(dx*t)), Y: p.Y + int(math.Round(dy*t))})
+	}
+}
+
+func (b *brush) polyline(pts []image.Point) {
+	if len(pts) == 1 {
+		b.stamp(pts[0])
+		return
+	}
+	for i := 1; i < len(pts); i++ {
+		b.line(pts[i-1], pts[i])
+	}
+}
+
+// arrow 画从 p 指向 q 的箭头，箭头两翼与主干成 30°
+func (b *brush) arrow(p, q image.Point) {
+	b.line(p, q)
+	dx, dy := float64(q.X-p.X), float64(q.Y-p.Y)
+	length := math.Hypot(dx, dy)
+	if length < 1 {
+		return
+	}
+	head := min(max(float64(b.radius)*8, 10), length/2)
+	angle := math.Atan2(dy, dx)
+	for _, side := range []float64{-1, 1} {
+		a := angle + math.Pi - side*math.Pi/6
+		b.line(q, image.Point{
+			X: q.X + int(math.Round(head*math.Cos(a))),
+			Y: q.Y + int(math.Round(head*math.Sin(a))),
+		})
+	}
+}
+
+func (b *brush) paint(dst *image.RGBA, c color.NRGBA) {
+	draw.DrawMask(dst, b.mask.Rect, image.NewUniform(c), image.Point{}, b.mask, b.mask.Rect.Min, draw.Over)
+}
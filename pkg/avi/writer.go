@@ -0,0 +1,290 @@
+// Package avi 以纯 Go 写出 Motion-JPEG AVI（AVI 1.0，带 idx1 索引），主流播放器和 ffmpeg 均可直接播放
+package avi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// 文件布局：
+//
+//	RIFF 'AVI '
+//	  LIST 'hdrl'
+//	    avih                 主文件头
+//	    LIST 'strl'
+//	      strh               视频流头，fccHandler = MJPG
+//	      strf               BITMAPINFOHEADER
+//	  LIST 'movi'
+//	    00dc …               每个时间槽一个块，空块表示重复上一帧
+//	  idx1                   每个块一条索引
+//
+// 帧数、各块大小等在 Close 时回填，因此要求底层支持 Seek。
+// AVI 采用固定帧率，WriteFrame 按帧的时间把它放进对应的时间槽，中间空出的槽写空块，
+// 播放时保持上一帧不动，这样帧率不稳定或暂停过的画面也能按原始节奏回放。
+
+// MaxSize 为单个文件的大小上限。AVI 1.0 的 RIFF 块和索引偏移都是 32 位，
+// 为兼容只接受 1 GB 以内 AVI 1.0 的播放器，留有余量
+const MaxSize = 1 << 30
+
+const (
+	// 各字段在文件中的偏移，布局见上
+	offRIFFSize       = 4
+	offAvihFrameTime  = 32
+	offAvihMaxBytes   = 36
+	offAvihFrames     = 48
+	offAvihBufferSize = 60
+	offStrhLength     = 140
+	offStrhBufferSize = 144
+	offMoviSize       = 216
+	offMovi           = 220 // 'movi' 标识的位置，idx1 中的偏移以此为基准
+	headerSize        = 224
+
+	indexEntrySize = 16
+	flagKeyFrame   = 0x10
+	flagHasIndex   = 0x10
+)
+
+// ErrTooLarge 表示继续写入会超过 MaxSize，应关闭当前文件另起一个
+var ErrTooLarge = errors.New("AVI 文件已达到大小上限")
+
+// indexEntry 为 idx1 中的一条记录，offset 相对 'movi' 标识
+type indexEntry struct {
+	offset uint32
+	size   uint32
+}
+
+// Writer 写出一个 MJPEG AVI 文件，不能并发调用
+type Writer struct {
+	ws     io.WriteSeeker
+	bw     *bufio.Writer
+	width  int
+	height int
+	fps    int
+
+	pos      int64 // 已写入的字节数
+	index    []indexEntry
+	frames   int // 非空帧数
+	maxChunk uint32
+	bytes    int64 // JPEG 数据总量，用于估算码率
+	closed   bool
+}
+
+// NewWriter 写出文件头并返回 Writer；fps 为名义帧率，决定时间精度。
+// 调用方在 Close 之后自行关闭 ws。
+func NewWriter(ws io.WriteSeeker, width, height, fps int) (*Writer, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("AVI 画面尺寸无效")
+	}
+	if fps <= 0 {
+		return nil, errors.New("AVI 帧率必须大于 0")
+	}
+	w := &Writer{ws: ws, bw: bufio.NewWriterSize(ws, 256<<10), width: width, height: height, fps: fps}
+	if _, err := w.bw.Write(w.header()); err != nil {
+		return nil, err
+	}
+	w.pos = headerSize
+	return w, nil
+}
+
+// header 生成文件头，大小、帧数等字段先填 0，Close 时回填
+func (w *Writer) header() []byte {
+	b := make([]byte, 0, headerSize)
+	le := binary.LittleEndian
+	u32 := func(v uint32) { b = le.AppendUint32(b, v) }
+	u16 := func(v uint16) { b = le.AppendUint16(b, v) }
+	fcc := func(s string) { b = append(b, s...) }
+
+	fcc("RIFF")
+	u32(0)
+	fcc("AVI ")
+
+	fcc("LIST")
+	u32(192)
+	fcc("hdrl")
+
+	fcc("avih")
+	u32(56)
+	u32(uint32(time.Second / time.Microsecond / time.Duration(w.fps))) // dwMicroSecPerFrame
+	u32(0)                                                             // dwMaxBytesPerSec
+	u32(0)                                                             // dwPaddingGranularity
+	u32(flagHasIndex)                                                  // dwFlags
+	u32(0)                                                             // dwTotalFrames
+	u32(0)                                                             // dwInitialFrames
+	u32(1)                                                             // dwStreams
+	u32(0)                                                             // dwSuggestedBufferSize
+	u32(uint32(w.width))
+	u32(uint32(w.height))
+	b = append(b, make([]byte, 16)...) // dwReserved
+
+	fcc("LIST")
+	u32(116)
+	fcc("strl")
+
+	fcc("strh")
+	u32(56)
+	fcc("vids")
+	fcc("MJPG")
+	u32(0)             // dwFlags
+	u16(0)             // wPriority
+	u16(0)             // wLanguage
+	u32(0)             // dwInitialFrames
+	u32(1)             // dwScale
+	u32(uint32(w.fps)) // dwRate，帧率 = dwRate / dwScale
+	u32(0)             // dwStart
+	u32(0)             // dwLength
+	u32(0)             // dwSuggestedBufferSize
+	u32(math.MaxUint32)
+	u32(0) // dwSampleSize
+	u16(0)
+	u16(0)
+	u16(uint16(w.width))
+	u16(uint16(w.height))
+
+	fcc("strf")
+	u32(40)
+	u32(40) // biSize
+	u32(uint32(w.width))
+	u32(uint32(w.height))
+	u16(1)  // biPlanes
+	u16(24) // biBitCount
+	fcc("MJPG")
+	u32(uint32(w.width * w.height * 3)) // biSizeImage
+	b = append(b, make([]byte, 16)...)  // 分辨率与调色板，均为 0
+
+	fcc("LIST")
+	u32(0)
+	fcc("movi")
+	return b
+}
+
+// Size 返回当前文件大小（含 Close 时才写出的索引）
+func (w *Writer) Size() int64 {
+	return w.pos + 8 + int64(len(w.index))*indexEntrySize
+}
+
+// Frames 返回写入的非空帧数
+func (w *Writer) Frames() int {
+	return w.frames
+}
+
+// Duration 返回已写入的时长
+func (w *Writer) Duration() time.Duration {
+	return time.Duration(len(w.index)) * time.Second / time.Duration(w.fps)
+}
+
+// WriteFrame 写入一帧 JPEG，at 为它相对文件开头的时间。与上一帧落在同一时间槽的帧被丢弃，
+// 返回 false；写入会超过 MaxSize 时返回 ErrTooLarge，文件保持完整，可以正常 Close
+func (w *Writer) WriteFrame(jpeg []byte, at time.Duration) (bool, error) {
+	if w.closed {
+		return false, errors.New("AVI 文件已关闭")
+	}
+	slot := int(math.Round(at.Seconds() * float64(w.fps)))
+	if slot < len(w.index) {
+		return false, nil
+	}
+	gap := int64(slot - len(w.index))
+	need := gap*(8+indexEntrySize) + 8 + int64(len(jpeg)+len(jpeg)&1) + indexEntrySize
+	if w.Size()+need > MaxSize {
+		return false, ErrTooLarge
+	}
+	for len(w.index) < slot {
+		if err := w.writeChunk(nil); err != nil {
+			return false, err
+		}
+	}
+	if err := w.writeChunk(jpeg); err != nil {
+		return false, err
+	}
+	w.frames++
+	w.bytes += int64(len(jpeg))
+	return true, nil
+}
+
+// writeChunk 写出一个 00dc 块，data 为空时写空块
+func (w *Writer) writeChunk(data []byte) error {
+	var hdr [8]byte
+	copy(hdr[:], "00dc")
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(data)))
+	if _, err := w.bw.Write(hdr[:]); err != nil {
+		return err
+	}
+	if _, err := w.bw.Write(data); err != nil {
+		return err
+	}
+	n := int64(8 + len(data))
+	// 块数据按 2 字节对齐
+	if len(data)&1 == 1 {
+		if err := w.bw.WriteByte(0); err != nil {
+			return err
+		}
+		n++
+	}
+	w.index = append(w.index, indexEntry{offset: uint32(w.pos - offMovi), size: uint32(len(data))})
+	w.maxChunk = max(w.maxChunk, uint32(len(data)))
+	w.pos += n
+	return nil
+}
+
+// Close 写出索引并回填文件头，不关闭底层的 ws
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	moviEnd := w.pos
+
+	le := binary.LittleEndian
+	idx := make([]byte, 0, 8+len(w.index)*indexEntrySize)
+	idx = append(idx, "idx1"...)
+	idx = le.AppendUint32(idx, uint32(len(w.index)*indexEntrySize))
+	for _, e := range w.index {
+		var flags uint32
+		if e.size > 0 {
+			flags = flagKeyFrame
+		}
+		idx = append(idx, "00dc"...)
+		idx = le.AppendUint32(idx, flags)
+		idx = le.AppendUint32(idx, e.offset)
+		idx = le.AppendUint32(idx, e.size)
+	}
+	if _, err := w.bw.Write(idx); err != nil {
+		return err
+	}
+	w.pos += int64(len(idx))
+	if err := w.bw.Flush(); err != nil {
+		return err
+	}
+
+	var maxBytes uint32
+	if d := w.Duration(); d > 0 {
+		maxBytes = uint32(float64(w.bytes) / d.Seconds())
+	}
+	patches := []struct {
+		off int64
+		v   uint32
+	}{
+		{offRIFFSize, uint32(w.pos - 8)},
+		{offAvihMaxBytes, maxBytes},
+		{offAvihFrames, uint32(len(w.index))},
+		{offAvihBufferSize, w.maxChunk},
+		{offStrhLength, uint32(len(w.index))},
+		{offStrhBufferSize, w.maxChunk},
+		{offMoviSize, uint32(moviEnd - offMoviSize - 4)},
+	}
+	for _, p := range patches {
+		if _, err := w.ws.Seek(p.off, io.SeekStart); err != nil {
+			return err
+		}
+		var b [4]byte
+		le.PutUint32(b[:], p.v)
+		if _, err := w.ws.Write(b[:]); err != nil {
+			return err
+		}
+	}
+	_, err := w.ws.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"snap-screen/pkg/annotate"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// 协作标注：Viewer 把标注发给 Publisher，Publisher 分配 ID、以连接的 peerID 作为作者，
+// 再转发给所有 Viewer（包括发送者本人），因此各端看到的标注与顺序一致。
+// 开启烧录时标注直接画进输出帧，Viewer 不再自行绘制。
+
+// annotate 消息的操作
+const (
+	annotateOpAdd    = "add"    // 双向：新增一条标注
+	annotateOpUndo   = "undo"   // Viewer → Publisher：撤销自己最近的一条
+	annotateOpRemove = "remove" // Publisher → Viewer：删除指定 ID
+	annotateOpClear  = "clear"  // 双向：清除全部
+	annotateOpSync   = "sync"   // Publisher → Viewer：连接建立时下发全部标注
+)
+
+// annotatePayload 为 annotate 控制消息的内容
+type annotatePayload struct {
+	Op         string               `json:"op"`
+	Annotation *annotate.Annotation `json:"annotation,omitempty"`
+	ID         uint64               `json:"id,omitempty"`
+	// By 为执行清除的一方，Publisher 本人清除时为空
+	By          string                `json:"by,omitempty"`
+	Annotations []annotate.Annotation `json:"annotations,omitempty"`
+	// Burned 表示标注已烧录进画面，Viewer 不必再绘制
+	Burned bool `json:"burned,omitempty"`
+}
+
+// Annotations 返回当前有效的标注
+func (s *Publisher) Annotations() []annotate.Annotation {
+	return s.board.Snapshot(time.Now())
+}
+
+// ClearAnnotations 清除全部标注并通知所有 Viewer
+func (s *Publisher) ClearAnnotations() {
+	s.board.Clear()
+	s.broadcastAnnotate(annotatePayload{Op: annotateOpClear})
+}
+
+// sendAnnotationSync 在 control 通道打开时把现有标注发给新 Viewer
+func (s *Publisher) sendAnnotationSync(dc *webrtc.DataChannel) error {
+	return sendControl(dc, controlTypeAnnotate, annotatePayload{
+		Op:          annotateOpSync,
+		Annotations: s.board.Snapshot(time.Now()),
+		Burned:      s.cfg.BurnAnnotations,
+	})
+}
+
+func (s *Publisher) handleAnnotate(peerID string, data json.RawMessage) {
+	var p annotatePayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("publisher parse annotate from", peerID, "error:", err)
+		return
+	}
+	switch p.Op {
+	case annotateOpAdd:
+		if p.Annotation == nil {
+			return
+		}
+		a := *p.Annotation
+		if err := a.Normalize(); err != nil {
+			log.Println("drop annotation from", peerID+":", err)
+			return
+		}
+		// ID 与作者以 Publisher 为准，Viewer 不能冒充他人或覆盖他人的标注
+		a.ID, a.Author, a.Age = 0, peerID, 0
+		stored := s.board.Add(a, time.Now())
+		s.broadcastAnnotate(annotatePayload{Op: annotateOpAdd, Annotation: &stored})
+	case annotateOpUndo:
+		if a, ok := s.board.Undo(peerID); ok {
+			s.broadcastAnnotate(annotatePayload{Op: annotateOpRemove, ID: a.ID})
+		}
+	case annotateOpClear:
+		s.board.Clear()
+		s.broadcastAnnotate(annotatePayload{Op: annotateOpClear, By: peerID})
+	}
+}
+
+// broadcastAnnotate 把标注变更转发给所有 Viewer
+func (s *Publisher) broadcastAnnotate(p annotatePayload) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for peerID, peer := range s.peers {
+		if err := sendControl(peer.ctrl, controlTypeAnnotate, p); err != nil {
+			log.Println("send annotate to", peerID, "failed:", err)
+		}
+	}
+}
+
+// -------------------- Viewer 侧 --------------------
+
+// SendAnnotation 把一条标注发给观看的 Publisher，ID 与作者由 Publisher 填写。
+// 标注经 Publisher 转发回来后才会出现在 ViewerConfig.Annotations 中。
+func (v *Viewer) SendAnnotation(a annotate.Annotation) error {
+	if err := a.Normalize(); err != nil {
+		return err
+	}
+	a.ID, a.Author, a.Age = 0, "", 0
+	return v.s.sendAnnotate(annotatePayload{Op: annotateOpAdd, Annotation: &a})
+}
+
+// UndoAnnotation 撤销自己最近的一条标注
+func (v *Viewer) UndoAnnotation() error {
+	return v.s.sendAnnotate(annotatePayload{Op: annotateOpUndo})
+}
+
+// ClearAnnotations 请求清除所有人的标注
+func (v *Viewer) ClearAnnotations() error {
+	return v.s.sendAnnotate(annotatePayload{Op: annotateOpClear})
+}
+
+func (s *viewerSession) sendAnnotate(p annotatePayload) error {
+	s.mu.Lock()
+	ctrl := s.ctrl
+	s.mu.Unlock()
+	if ctrl == nil || ctrl.ReadyState() != webrtc.DataChannelStateOpen {
+		return errors.New("control 通道尚未就绪")
+	}
+	return sendControl(ctrl, controlTypeAnnotate, p)
+}
+
+func (s *viewerSession) handleAnnotate(data json.RawMessage) {
+	var p annotatePayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("viewer parse annotate error:", err)
+		return
+	}
+	board := s.annotations
+	if board == nil {
+		return
+	}
+	now := time.Now()
+	switch p.Op {
+	case annotateOpSync:
+		s.mu.Lock()
+		s.annotationsBurned = p.Burned
+		s.mu.Unlock()
+		board.Clear()
+		if !p.Burned {
+			for _, a := range p.Annotations {
+				board.Add(a, now)
+			}
+		}
+	case annotateOpAdd:
+		s.mu.Lock()
+		burned := s.annotationsBurned
+		s.mu.Unlock()
+		if p.Annotation != nil && !burned {
+			board.Add(*p.Annotation, now)
+		}
+	case annotateOpRemove:
+		board.Remove(p.ID)
+	case annotateOpClear:
+		board.Clear()
+		if s.onCleared != nil {
+			s.onCleared(p.By)
+		}
+	}
+}
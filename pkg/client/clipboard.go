@@ -0,0 +1,160 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"snap-screen/pkg/clipboard"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// 剪贴板共享：双方都需显式开启（配置 Clipboard）。Publisher 可以随时把文本或图片推给所有 Viewer；
+// Viewer 只有在 Publisher 允许（AcceptViewerClipboard）时才能回传，Publisher 在连接建立时告知是否允许。
+// 每次传输都会通过 OnClipboard 回调通知，由界面提示用户。
+
+// clipboardPayload 为 clipboard 控制消息的内容。Policy 不为 nil 时为 Publisher 下发的策略，
+// 否则为一次剪贴板传输；Image 在 JSON 中以 base64 编码
+type clipboardPayload struct {
+	Text  string `json:"text,omitempty"`
+	Image []byte `json:"image,omitempty"`
+
+	Policy *clipboardPolicy `json:"policy,omitempty"`
+}
+
+type clipboardPolicy struct {
+	// Upload 表示 Publisher 接受 Viewer 回传剪贴板
+	Upload bool `json:"upload"`
+	// MaxText / MaxImage 为 Publisher 接受的大小上限，Viewer 发送前据此检查
+	MaxText  int `json:"max_text,omitempty"`
+	MaxImage int `json:"max_image,omitempty"`
+}
+
+// PushClipboard 把内容推送给所有已连接的 Viewer，返回成功发送的 Viewer 数量。
+// 未配置 Clipboard 时返回错误。
+func (s *Publisher) PushClipboard(c clipboard.Content) (int, error) {
+	if s.cfg.Clipboard == nil {
+		return 0, errors.New("未开启剪贴板共享")
+	}
+	if err := s.cfg.ClipboardLimits.Check(c); err != nil {
+		return 0, err
+	}
+	p := clipboardPayload{Text: c.Text, Image: c.Image}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sent := 0
+	var lastErr error
+	for peerID, peer := range s.peers {
+		if peer.ctrl == nil || peer.ctrl.ReadyState() != webrtc.DataChannelStateOpen {
+			continue
+		}
+		if err := sendControl(peer.ctrl, controlTypeClipboard, p); err != nil {
+			log.Println("send clipboard to", peerID, "failed:", err)
+			lastErr = err
+			continue
+		}
+		sent++
+	}
+	if sent == 0 && lastErr != nil {
+		return 0, lastErr
+	}
+	return sent, nil
+}
+
+// sendClipboardPolicy 在 control 通道打开时告知 Viewer 是否可以回传剪贴板
+func (s *Publisher) sendClipboardPolicy(dc *webrtc.DataChannel) error {
+	if s.cfg.Clipboard == nil {
+		return nil
+	}
+	return sendControl(dc, controlTypeClipboard, clipboardPayload{Policy: &clipboardPolicy{
+		Upload:   s.cfg.AcceptViewerClipboard,
+		MaxText:  s.cfg.ClipboardLimits.MaxText,
+		MaxImage: s.cfg.ClipboardLimits.MaxImage,
+	}})
+}
+
+// handleClipboard 处理 Viewer 回传的剪贴板，未允许回传时直接丢弃
+func (s *Publisher) handleClipboard(peerID string, data json.RawMessage) {
+	if s.cfg.Clipboard == nil || !s.cfg.AcceptViewerClipboard {
+		log.Println("drop clipboard from", peerID+": upload not allowed")
+		return
+	}
+	var p clipboardPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("publisher parse clipboard from", peerID, "error:", err)
+		return
+	}
+	c := clipboard.Content{Text: p.Text, Image: p.Image}
+	err := s.cfg.ClipboardLimits.Check(c)
+	if err == nil {
+		err = s.cfg.Clipboard.Write(c)
+	}
+	if err != nil {
+		log.Println("apply clipboard from", peerID, "failed:", err)
+	}
+	if s.cfg.OnClipboard != nil {
+		s.cfg.OnClipboard(peerID, c, err)
+	}
+}
+
+// -------------------- Viewer 侧 --------------------
+
+// SendClipboard 把内容发给观看的 Publisher，对方未允许回传时返回错误
+func (v *Viewer) SendClipboard(c clipboard.Content) error {
+	s := v.s
+	s.mu.Lock()
+	ctrl := s.ctrl
+	policy := s.clipboardPolicy
+	s.mu.Unlock()
+	if s.clipboard == nil {
+		return errors.New("未开启剪贴板共享")
+	}
+	if policy == nil || !policy.Upload {
+		return errors.New("对方不接受回传剪贴板")
+	}
+	if err := (clipboard.Limits{MaxText: policy.MaxText, MaxImage: policy.MaxImage}).Check(c); err != nil {
+		return err
+	}
+	if err := s.clipboardLimits.Check(c); err != nil {
+		return err
+	}
+	if ctrl == nil || ctrl.ReadyState() != webrtc.DataChannelStateOpen {
+		return errors.New("control 通道尚未就绪")
+	}
+	return sendControl(ctrl, controlTypeClipboard, clipboardPayload{Text: c.Text, Image: c.Image})
+}
+
+func (s *viewerSession) handleClipboard(data json.RawMessage) {
+	if s.clipboard == nil {
+		return
+	}
+	var p clipboardPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("viewer parse clipboard error:", err)
+		return
+	}
+	if p.Policy != nil {
+		s.mu.Lock()
+		s.clipboardPolicy = p.Policy
+		s.mu.Unlock()
+		if s.onClipboardPolicy != nil {
+			s.onClipboardPolicy(p.Policy.Upload)
+		}
+		return
+	}
+
+	c := clipboard.Content{Text: p.Text, Image: p.Image}
+	err := s.clipboardLimits.Check(c)
+	if err == nil {
+		// 不支持图片的剪贴板（如 fyne）会返回 ErrImageUnsupported，由回调决定是否另存为文件
+		err = s.clipboard.Write(c)
+	}
+	if err != nil && !errors.Is(err, clipboard.ErrImageUnsupported) {
+		log.Println("apply clipboard failed:", err)
+	}
+	if s.onClipboard != nil {
+		s.onClipboard(c, err)
+	}
+}
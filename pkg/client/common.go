@@ -5,6 +5,13 @@ import (
 	"errors"
 	"time"
 
+	"snap-screen/pkg/annotate"
+	"snap-screen/pkg/clipboard"
+	"snap-screen/pkg/codec"
+	"snap-screen/pkg/input"
+	"snap-screen/pkg/overlay"
+	"snap-screen/pkg/transfer"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -16,6 +23,7 @@ const (
 	PublisherStatusConnected    PublisherStatus = "已连接"
 	PublisherStatusError        PublisherStatus = "错误"
 	PublisherStatusRunning      PublisherStatus = "推流中"
+	PublisherStatusPaused       PublisherStatus = "已暂停"
 	PublisherStatusStopped      PublisherStatus = "已停止"
 )
 
@@ -25,6 +33,85 @@ type PublisherConfig struct {
 	FrameRate int
 	Width     int
 	Height    int
+	// ICE 控制 STUN / TURN 服务器及候选收集策略，零值使用默认公共 STUN
+	ICE ICEConfig
+	// Placeholder 为暂停分享期间发送的占位画面，零值为提示文字画面
+	Placeholder PlaceholderConfig
+	// Overlay 为缩放之后、编码之前叠加到每一帧上的水印；Name 为空时使用 streamID
+	Overlay overlay.Config
+	// Codec 为首选帧编码，Viewer 不支持时该 Viewer 退回 JPEG；零值为 JPEG
+	Codec codec.ID
+	// JPEGQuality 为 JPEG 编码质量（1~100），零值为 60
+	JPEGQuality int
+	// Input 用于远程控制时注入键鼠事件，nil 表示不允许远程控制；由调用方负责关闭
+	Input input.InputInjector
+	// OnControlRequest 在 Viewer 申请远程控制时回调，同意后调用 Publisher.GrantControl
+	OnControlRequest func(peerID string)
+	// BurnAnnotations 为 true 时把 Viewer 的标注烧录进输出帧（录屏、旧版 Viewer 也能看到），
+	// Viewer 不再自行绘制
+	BurnAnnotations bool
+	// Clipboard 不为 nil 时开启剪贴板共享，可通过 Publisher.PushClipboard 推送给 Viewer
+	Clipboard clipboard.Clipboard
+	// AcceptViewerClipboard 为 true 时接受 Viewer 回传的剪贴板并写入 Clipboard
+	AcceptViewerClipboard bool
+	// ClipboardLimits 为单次传输的大小上限，零值使用默认值
+	ClipboardLimits clipboard.Limits
+	// OnClipboard 在收到 Viewer 回传的剪贴板时回调，err 不为 nil 表示内容被拒绝或写入失败
+	OnClipboard func(peerID string, c clipboard.Content, err error)
+	// OnFileOffer 在 Viewer 发来文件时回调，之后调用 AcceptFile / RejectFile；nil 表示不接收文件
+	OnFileOffer func(peerID string, offer transfer.Offer)
+	// OnTransfer 报告与各 Viewer 之间文件收发的进度
+	OnTransfer func(peerID string, p transfer.Progress)
+	// DisableSnapshots 为 true 时拒绝 Viewer 的原始分辨率截图请求
+	DisableSnapshots bool
+	// SnapshotInterval 为同一 Viewer 两次截图请求的最小间隔，零值为 5 秒，负值不限制
+	SnapshotInterval time.Duration
+	// OnViewerRecording 在 Viewer 开始（recording 为 true）或停止录制时回调，可为 nil
+	OnViewerRecording func(peerID string, recording bool)
+}
+
+// ViewerConfig 控制观看侧的基础参数
+type ViewerConfig struct {
+	SignalURL string
+	ICE       ICEConfig
+	// OnStreamState 在 Publisher 暂停 / 恢复分享时回调，可为 nil
+	OnStreamState func(StreamState)
+	// Codecs 为允许协商的帧编码，空时为本端支持的全部编码
+	Codecs []codec.ID
+	// OnLatency 在收到 Publisher 测得的端到端延迟时回调（约每秒一次），可为 nil
+	OnLatency func(time.Duration)
+	// OnRemoteControl 在远程控制权被授予（enabled 为 true）、拒绝或收回时回调
+	OnRemoteControl func(enabled bool, reason string)
+	// Annotations 为 nil 时忽略标注；否则会话把 Publisher 转发的标注同步到其中，由调用方绘制
+	Annotations *annotate.Board
+	// OnAnnotationsCleared 在有人清除全部标注时回调，by 为清除者的 peerID，Publisher 清除时为空
+	OnAnnotationsCleared func(by string)
+	// Clipboard 不为 nil 时接受 Publisher 推送的剪贴板并写入其中，也可通过 SendClipboard 回传
+	Clipboard clipboard.Clipboard
+	// ClipboardLimits 为接受的单次传输大小上限，零值使用默认值
+	ClipboardLimits clipboard.Limits
+	// OnClipboard 在收到 Publisher 推送的剪贴板时回调；Clipboard 不支持图片（如 fyne 剪贴板）时
+	// err 为 clipboard.ErrImageUnsupported，可改为另存为文件
+	OnClipboard func(c clipboard.Content, err error)
+	// OnClipboardPolicy 在得知 Publisher 是否接受回传时回调
+	OnClipboardPolicy func(upload bool)
+	// OnFileOffer 在 Publisher 发来文件时回调，之后调用 AcceptFile / RejectFile；nil 表示不接收文件
+	OnFileOffer func(offer transfer.Offer)
+	// OnTransfer 报告文件收发的进度（包括截图的传输）
+	OnTransfer func(p transfer.Progress)
+	// OnSnapshot 在 RequestSnapshot 请求的截图保存完成（path 为 PNG 路径）或失败时回调
+	OnSnapshot func(path string, err error)
+	// SnapshotDir 为截图的保存目录，空时为系统临时目录下的 snap-screen/snapshots
+	SnapshotDir string
+	// OnZoom 在画面显示的区域变化时回调（Publisher 开始发送 SetZoom 请求的区域或恢复完整画面），
+	// 调用方据此把画面上的坐标换算回完整画面
+	OnZoom func(ZoomRegion)
+	// OnViewerState 在连接状态变化时回调，detail 为展示给用户的说明
+	OnViewerState func(st ViewerState, detail string)
+	// Reconnect 控制断线后的自动重连，零值为按 1~30 秒退避一直重试
+	Reconnect ReconnectConfig
+	// Sink 不为 nil 时每一帧解码后交给它（例如界面上的图像组件），另见 Viewer.Frames
+	Sink FrameSink
 }
 
 // signalMessage 是客户端与信令服务器之间的 JSON 消息结构
@@ -58,6 +145,12 @@ func normalizeConfig(cfg *PublisherConfig) {
 	if cfg.Height < 0 {
 		cfg.Height = 0
 	}
+	if cfg.Codec == 0 {
+		cfg.Codec = codec.JPEG
+	}
+	if cfg.JPEGQuality <= 0 || cfg.JPEGQuality > 100 {
+		cfg.JPEGQuality = codec.DefaultJPEGQuality
+	}
 }
 
 // FetchStreamList 拉取当前可用的流 ID 列表
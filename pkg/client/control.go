@@ -0,0 +1,152 @@
+package client
+
+import (
+	"encoding/json"
+	"log"
+
+	"snap-screen/pkg/codec"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// Viewer 作为 Offer 端创建的 DataChannel 标签
+const (
+	frameChannelLabel   = "screen-frames"
+	controlChannelLabel = "control"
+	inputChannelLabel   = "input" // 远程控制的键鼠事件
+	fileChannelLabel    = "files" // 双向文件传输
+)
+
+// StreamState 表示 Publisher 推流画面的状态，通过 control 通道通知 Viewer
+type StreamState string
+
+const (
+	StreamStateLive   StreamState = "live"
+	StreamStatePaused StreamState = "paused"
+)
+
+// control 通道消息类型
+const (
+	controlTypeState     = "state"
+	controlTypeHello     = "hello"   // Viewer → Publisher，告知支持的帧编码
+	controlTypePing      = "ping"    // Publisher → Viewer，对时请求
+	controlTypePong      = "pong"    // Viewer → Publisher，对时应答
+	controlTypeAck       = "ack"     // Viewer → Publisher，确认最近显示的一帧
+	controlTypeLatency   = "latency" // Publisher → Viewer，端到端延迟测量结果
+	controlTypeRemote    = "remote_control"
+	controlTypeAnnotate  = "annotate"  // 双向，协作标注，见 annotate.go
+	controlTypeClipboard = "clipboard" // 双向，剪贴板共享，见 clipboard.go
+
+	controlTypeRequestSnapshot = "request_snapshot" // Viewer → Publisher，请求原始分辨率截图，见 snapshot.go
+	controlTypeSnapshot        = "snapshot"         // Publisher → Viewer，截图被拒绝或失败
+	controlTypeZoom            = "zoom"             // Viewer → Publisher，请求放大的区域，见 zoom.go
+	controlTypeRecording       = "recording"        // Viewer → Publisher，开始 / 停止录制，见 recording.go
+)
+
+// helloPayload 是 hello 控制消息的内容，Codecs 按 Viewer 的偏好排列
+type helloPayload struct {
+	Codecs []string `json:"codecs"`
+}
+
+// controlMessage 是 control DataChannel 上传输的 JSON 消息
+type controlMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// sendControl 向 control 通道发送一条消息，通道未就绪时静默丢弃
+func sendControl(dc *webrtc.DataChannel, typ string, payload interface{}) error {
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return nil
+	}
+	msg := controlMessage{Type: typ}
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Data = b
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return dc.SendText(string(b))
+}
+
+// handleControlChannel 处理 Viewer 创建的 control 通道
+func (s *Publisher) handleControlChannel(peerID string, dc *webrtc.DataChannel) {
+	dc.OnOpen(func() {
+		// 新连接的 Viewer 需要立即知道当前是否处于暂停状态
+		if err := sendControl(dc, controlTypeState, statePayload{State: s.streamState()}); err != nil {
+			log.Println("send initial state to", peerID, "failed:", err)
+		}
+		if err := s.sendAnnotationSync(dc); err != nil {
+			log.Println("send annotations to", peerID, "failed:", err)
+		}
+		if err := s.sendClipboardPolicy(dc); err != nil {
+			log.Println("send clipboard policy to", peerID, "failed:", err)
+		}
+	})
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		s.handleControl(peerID, msg.Data)
+	})
+}
+
+func (s *Publisher) handleControl(peerID string, data []byte) {
+	var msg controlMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Println("publisher parse control from", peerID, "error:", err)
+		return
+	}
+	switch msg.Type {
+	case controlTypeHello:
+		var hello helloPayload
+		if err := json.Unmarshal(msg.Data, &hello); err != nil {
+			log.Println("publisher parse hello from", peerID, "error:", err)
+			return
+		}
+		id := s.negotiateCodec(hello.Codecs)
+		s.mu.Lock()
+		if peer, ok := s.peers[peerID]; ok {
+			peer.codec = id
+		}
+		s.mu.Unlock()
+		log.Println("viewer", peerID, "negotiated codec:", id)
+	case controlTypePong:
+		s.handlePong(peerID, msg.Data)
+	case controlTypeAck:
+		s.handleAck(peerID, msg.Data)
+	case controlTypeRemote:
+		s.handleRemoteRequest(peerID, msg.Data)
+	case controlTypeAnnotate:
+		s.handleAnnotate(peerID, msg.Data)
+	case controlTypeClipboard:
+		s.handleClipboard(peerID, msg.Data)
+	case controlTypeRequestSnapshot:
+		s.handleSnapshotRequest(peerID)
+	case controlTypeZoom:
+		s.handleZoom(peerID, msg.Data)
+	case controlTypeRecording:
+		s.handleRecording(peerID, msg.Data)
+	}
+}
+
+// negotiateCodec 在 Viewer 支持首选编码时使用首选编码，否则退回所有端都支持的 JPEG
+func (s *Publisher) negotiateCodec(names []string) codec.ID {
+	for _, name := range names {
+		if id, err := codec.ParseID(name); err == nil && id == s.cfg.Codec {
+			return id
+		}
+	}
+	return codec.JPEG
+}
+
+// sendHello 在 control 通道打开后由 Viewer 调用
+func sendHello(dc *webrtc.DataChannel, codecs []codec.ID) error {
+	names := make([]string, 0, len(codecs))
+	for _, id := range codecs {
+		names = append(names, id.String())
+	}
+	return sendControl(dc, controlTypeHello, helloPayload{Codecs: names})
+}
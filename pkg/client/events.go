@@ -0,0 +1,142 @@
+package client
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// ViewerEventType 为 Viewer 事件的类型
+type ViewerEventType string
+
+const (
+	// ViewerEventConnected 表示与 Publisher 的 WebRTC 连接已建立
+	ViewerEventConnected ViewerEventType = "connected"
+	// ViewerEventDisconnected 表示 WebRTC 连接中断或失败，Err 为原因
+	ViewerEventDisconnected ViewerEventType = "disconnected"
+	// ViewerEventStreamState 表示 Publisher 暂停或恢复了分享，见 State
+	ViewerEventStreamState ViewerEventType = "stream_state"
+	// ViewerEventStreamRemoved 表示 stream 不存在或已被 Publisher 注销，Err 为 ErrStreamNotFound 或 ErrStreamRemoved
+	ViewerEventStreamRemoved ViewerEventType = "stream_removed"
+	// ViewerEventState 表示连接状态变化，见 ViewerState / Detail，ended 时 Err 为结束原因
+	ViewerEventState ViewerEventType = "state"
+	// ViewerEventError 为其他错误，例如信令连接断开、信令服务器返回的错误
+	ViewerEventError ViewerEventType = "error"
+	// ViewerEventClosed 为会话结束后的最后一个事件，之后 Events 通道关闭
+	ViewerEventClosed ViewerEventType = "closed"
+)
+
+// 信令服务器返回的 stream 相关错误
+var (
+	ErrStreamNotFound = errors.New("stream 不存在")
+	ErrStreamRemoved  = errors.New("stream 已被 Publisher 注销")
+)
+
+// ViewerEvent 为 Viewer.Events 通道中的一个事件
+type ViewerEvent struct {
+	Type ViewerEventType
+	Time time.Time
+	// State 只在 ViewerEventStreamState 时有效
+	State StreamState
+	// ViewerState / Detail 只在 ViewerEventState 时有效
+	ViewerState ViewerState
+	Detail      string
+	Err         error
+}
+
+const (
+	// Events 通道的缓冲，调用方读取不及时时丢弃新事件，避免阻塞会话
+	viewerEventBuffer = 64
+	// Frames 通道只保留最新一帧
+	viewerFrameBuffer = 1
+)
+
+// emit 发送事件；通道已满时丢弃并计数，不输出日志（只用 Frames 或 Sink 的调用方可以不读取 Events），
+// 会话结束后忽略
+func (s *viewerSession) emit(ev ViewerEvent) {
+	ev.Time = time.Now()
+	s.chanMu.Lock()
+	defer s.chanMu.Unlock()
+	if s.chansClosed {
+		return
+	}
+	select {
+	case s.events <- ev:
+	default:
+		s.droppedEvents++
+	}
+}
+
+// deliverFrame 把一帧交给 Sink 和录制的 sink，并放入 Frames 通道；通道中未取走的旧帧会被替换
+func (s *viewerSession) deliverFrame(f Frame) {
+	// sinkMu 保证 StopRecording 返回后不会再有帧交给录制的 sink
+	s.sinkMu.Lock()
+	s.mu.Lock()
+	rec := s.recorder
+	s.mu.Unlock()
+	if s.sink != nil {
+		if err := s.sink.WriteFrame(f); err != nil {
+			log.Println("viewer write frame to sink error:", err)
+		}
+	}
+	if rec != nil {
+		if err := rec.WriteFrame(f); err != nil {
+			log.Println("viewer record frame error:", err)
+		}
+	}
+	s.sinkMu.Unlock()
+
+	s.chanMu.Lock()
+	defer s.chanMu.Unlock()
+	if s.chansClosed {
+		return
+	}
+	select {
+	case s.frames <- f:
+		return
+	default:
+	}
+	// 只有本函数发送，且在锁内，取走旧帧后一定能放入
+	select {
+	case <-s.frames:
+	default:
+	}
+	s.frames <- f
+}
+
+// closeChans 发出 ViewerEventClosed 并关闭 Frames / Events 通道，可重复调用
+func (s *viewerSession) closeChans() {
+	s.emit(ViewerEvent{Type: ViewerEventClosed})
+	s.chanMu.Lock()
+	defer s.chanMu.Unlock()
+	if s.chansClosed {
+		return
+	}
+	s.chansClosed = true
+	close(s.frames)
+	close(s.events)
+}
+
+// signalError 把信令服务器返回的错误转换为事件；stream 不存在或被移除时放弃当前连接，等待重连
+func (s *viewerSession) signalError(gen int, msg string) {
+	if msg == "stream not found" || msg == "stream removed" {
+		// 订阅、Offer、ICE 候选都会各收到一次，每个连接只报告一次
+		s.mu.Lock()
+		dup := s.removedGen == gen
+		s.removedGen = gen
+		s.mu.Unlock()
+		if dup {
+			return
+		}
+	}
+	switch msg {
+	case "stream not found":
+		s.emit(ViewerEvent{Type: ViewerEventStreamRemoved, Err: ErrStreamNotFound})
+		s.fail(gen, ErrStreamNotFound)
+	case "stream removed":
+		s.emit(ViewerEvent{Type: ViewerEventStreamRemoved, Err: ErrStreamRemoved})
+		s.fail(gen, ErrStreamRemoved)
+	default:
+		s.emit(ViewerEvent{Type: ViewerEventError, Err: errors.New(msg)})
+	}
+}
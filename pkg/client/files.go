@@ -0,0 +1,150 @@
+package client
+
+import (
+	"errors"
+
+	"snap-screen/pkg/transfer"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// 文件传输：Viewer 在 screen-frames 旁边再创建一条 files 通道，双方都可以发送文件，
+// 分块、流控、断点续传与 SHA-256 校验由 transfer.Manager 负责。
+// 接收方必须配置 OnFileOffer 并显式 Accept，否则对方的文件会被自动拒绝。
+
+// newFileManager 为 Viewer 的 files 通道创建 Publisher 侧的传输管理器
+func (s *Publisher) newFileManager(peerID string, dc *webrtc.DataChannel) *transfer.Manager {
+	cfg := transfer.Config{}
+	if s.cfg.OnFileOffer != nil {
+		cfg.OnOffer = func(o transfer.Offer) { s.cfg.OnFileOffer(peerID, o) }
+	}
+	if s.cfg.OnTransfer != nil {
+		cfg.OnProgress = func(p transfer.Progress) { s.cfg.OnTransfer(peerID, p) }
+	}
+	m := transfer.NewManager(dc, cfg)
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		m.HandleMessage(msg.IsString, msg.Data)
+	})
+	dc.OnClose(m.Close)
+	return m
+}
+
+func (s *Publisher) fileManager(peerID string) (*transfer.Manager, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	peer, ok := s.peers[peerID]
+	if !ok {
+		return nil, errors.New("Viewer 不存在: " + peerID)
+	}
+	if peer.files == nil {
+		return nil, errors.New("该 Viewer 不支持文件传输")
+	}
+	return peer.files, nil
+}
+
+// SendFile 向指定 Viewer 发送文件，对方接受后在后台传输，返回传输 ID
+func (s *Publisher) SendFile(peerID, path string) (string, error) {
+	m, err := s.fileManager(peerID)
+	if err != nil {
+		return "", err
+	}
+	return m.SendFile(path)
+}
+
+// AcceptFile 接受 Viewer 发来的文件并保存到 dir 目录
+func (s *Publisher) AcceptFile(peerID, id, dir string) error {
+	m, err := s.fileManager(peerID)
+	if err != nil {
+		return err
+	}
+	return m.Accept(id, dir)
+}
+
+// RejectFile 拒绝 Viewer 发来的文件
+func (s *Publisher) RejectFile(peerID, id string) error {
+	m, err := s.fileManager(peerID)
+	if err != nil {
+		return err
+	}
+	return m.Reject(id, "")
+}
+
+// CancelTransfer 取消与指定 Viewer 之间的一次收发
+func (s *Publisher) CancelTransfer(peerID, id string) error {
+	m, err := s.fileManager(peerID)
+	if err != nil {
+		return err
+	}
+	return m.Cancel(id)
+}
+
+// -------------------- Viewer 侧 --------------------
+
+func (v *Viewer) fileManager() (*transfer.Manager, error) {
+	s := v.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		return nil, errors.New("文件通道尚未建立")
+	}
+	return s.files, nil
+}
+
+// SendFile 向观看的 Publisher 发送文件，返回传输 ID
+func (v *Viewer) SendFile(path string) (string, error) {
+	m, err := v.fileManager()
+	if err != nil {
+		return "", err
+	}
+	return m.SendFile(path)
+}
+
+// AcceptFile 接受 Publisher 发来的文件并保存到 dir 目录
+func (v *Viewer) AcceptFile(id, dir string) error {
+	m, err := v.fileManager()
+	if err != nil {
+		return err
+	}
+	return m.Accept(id, dir)
+}
+
+// RejectFile 拒绝 Publisher 发来的文件
+func (v *Viewer) RejectFile(id string) error {
+	m, err := v.fileManager()
+	if err != nil {
+		return err
+	}
+	return m.Reject(id, "")
+}
+
+// CancelTransfer 取消一次收发
+func (v *Viewer) CancelTransfer(id string) error {
+	m, err := v.fileManager()
+	if err != nil {
+		return err
+	}
+	return m.Cancel(id)
+}
+
+// createFileChannel 创建 files 通道及其传输管理器
+func (s *viewerSession) createFileChannel(pc *webrtc.PeerConnection) error {
+	dc, err := pc.CreateDataChannel(fileChannelLabel, nil)
+	if err != nil {
+		return err
+	}
+	// 截图也经 files 通道传输，因此 OnOffer 总是设置，未配置 OnFileOffer 时由 handleFileOffer 拒绝普通文件
+	var m *transfer.Manager
+	m = transfer.NewManager(dc, transfer.Config{
+		OnOffer:    func(o transfer.Offer) { s.handleFileOffer(m, o) },
+		OnProgress: s.handleTransfer,
+	})
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		m.HandleMessage(msg.IsString, msg.Data)
+	})
+	dc.OnClose(m.Close)
+
+	s.mu.Lock()
+	s.filesDC, s.files = dc, m
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+	"sync"
+
+	"snap-screen/pkg/codec"
+)
+
+// 帧数据格式：
+//
+//	magic(1) | headerLen(1) | codec(1) | seq(4, 大端) | strips(1) | captured(8, 大端) | 帧体
+//
+// headerLen 为整个帧头的长度，后续版本可以在已有字段之后追加字段，
+// 旧版本解析时按 headerLen 跳过即可。
+//
+// captured 为 Publisher 采集该帧时的 Unix 纳秒时间戳，占位帧为 0。
+// 放大画面（见 zoom.go）在其后追加 zoom(8)：x / y / w / h 四个 uint16（大端，65535 表示 1），
+// 此时 headerLen 为 24；完整画面不带该字段。
+// strips 为 1 时帧体就是编码后的整幅图像；大于 1 时帧体为
+// strips 个 uint32（大端）条带长度，之后依次是自上而下各条带独立编码的数据。
+const (
+	frameMagic          = 0x53
+	frameHeaderSize     = 16
+	frameHeaderSizeZoom = 24 // 带放大区域的帧头长度
+	maxFrameStrips      = 64
+)
+
+// frameHeader 是每一帧 DataChannel 消息前的固定帧头
+type frameHeader struct {
+	Codec    codec.ID
+	Seq      uint32
+	Strips   int
+	Captured int64
+	// Region 为该帧显示的区域，零值为完整画面
+	Region ZoomRegion
+}
+
+// marshalFrame 把帧头和各条带的编码数据拼接成一条 DataChannel 消息
+func marshalFrame(h frameHeader, parts [][]byte) []byte {
+	hs := frameHeaderSize
+	if !h.Region.Full() {
+		hs = frameHeaderSizeZoom
+	}
+	n := hs
+	if len(parts) > 1 {
+		n += 4 * len(parts)
+	}
+	for _, p := range parts {
+		n += len(p)
+	}
+	b := make([]byte, hs, n)
+	b[0] = frameMagic
+	b[1] = byte(hs)
+	b[2] = byte(h.Codec)
+	binary.BigEndian.PutUint32(b[3:7], h.Seq)
+	b[7] = byte(len(parts))
+	binary.BigEndian.PutUint64(b[8:16], uint64(h.Captured))
+	if hs == frameHeaderSizeZoom {
+		r := h.Region
+		for i, v := range []float64{r.X, r.Y, r.W, r.H} {
+			binary.BigEndian.PutUint16(b[16+2*i:], uint16(math.Round(v*zoomQuantum)))
+		}
+	}
+	if len(parts) > 1 {
+		for _, p := range parts {
+			b = binary.BigEndian.AppendUint32(b, uint32(len(p)))
+		}
+	}
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// unmarshalFrame 解析帧头，返回帧头和各条带的编码数据
+func unmarshalFrame(data []byte) (frameHeader, [][]byte, error) {
+	if len(data) < 7 || data[0] != frameMagic {
+		return frameHeader{}, nil, errors.New("invalid frame header")
+	}
+	n := int(data[1])
+	if n < 7 || n > len(data) {
+		return frameHeader{}, nil, errors.New("invalid frame header length")
+	}
+	h := frameHeader{
+		Codec:  codec.ID(data[2]),
+		Seq:    binary.BigEndian.Uint32(data[3:7]),
+		Strips: 1,
+	}
+	if n >= 8 && data[7] > 1 {
+		h.Strips = int(data[7])
+	}
+	if n >= 16 {
+		h.Captured = int64(binary.BigEndian.Uint64(data[8:16]))
+	}
+	if n >= frameHeaderSizeZoom {
+		var v [4]float64
+		for i := range v {
+			v[i] = float64(binary.BigEndian.Uint16(data[16+2*i:])) / zoomQuantum
+		}
+		h.Region = ZoomRegion{X: v[0], Y: v[1], W: v[2], H: v[3]}.Normalize()
+	}
+	body := data[n:]
+	if h.Strips == 1 {
+		return h, [][]byte{body}, nil
+	}
+	if h.Strips > maxFrameStrips || len(body) < 4*h.Strips {
+		return frameHeader{}, nil, errors.New("invalid frame strip table")
+	}
+	parts := make([][]byte, h.Strips)
+	off := 4 * h.Strips
+	for i := range parts {
+		size := int(binary.BigEndian.Uint32(body[4*i:]))
+		if size > len(body)-off {
+			return frameHeader{}, nil, errors.New("truncated frame strip")
+		}
+		parts[i] = body[off : off+size]
+		off += size
+	}
+	return h, parts, nil
+}
+
+// decodeStrips 并行解码各条带并自上而下拼接为一幅图像
+func decodeStrips(dec codec.Decoder, parts [][]byte) (image.Image, error) {
+	if len(parts) == 1 {
+		return dec.Decode(parts[0])
+	}
+	imgs := make([]image.Image, len(parts))
+	errs := make([]error, len(parts))
+	var wg sync.WaitGroup
+	for i, p := range parts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			imgs[i], errs[i] = dec.Decode(p)
+		}()
+	}
+	wg.Wait()
+
+	width, height := 0, 0
+	for i, img := range imgs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		width = max(width, img.Bounds().Dx())
+		height += img.Bounds().Dy()
+	}
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	y := 0
+	for _, img := range imgs {
+		b := img.Bounds()
+		draw.Draw(out, image.Rect(0, y, b.Dx(), y+b.Dy()), img, b.Min, draw.Src)
+		y += b.Dy()
+	}
+	return out, nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pion/ice/v4"
+	"github.com/pion/webrtc/v4"
+)
+
+// 未配置任何 ICE 服务器时使用的默认 STUN
+var defaultICEServers = []ICEServer{
+	{URLs: []string{"stun:stun.l.google.com:19302"}},
+}
+
+// ICEServer 描述一个 STUN / TURN 服务器，TURN 需要填写用户名和凭据
+type ICEServer struct {
+	URLs       []string
+	Username   string
+	Credential string
+}
+
+// ICEConfig 控制 Publisher / Viewer 建立 WebRTC 连接时的 ICE 行为
+type ICEConfig struct {
+	// Servers 为空时使用默认公共 STUN（LANOnly 模式下忽略）
+	Servers []ICEServer
+	// TransportPolicy 取值 "all"（默认）、"relay"（仅走 TURN 中继）或 "nohost"
+	TransportPolicy string
+	// NetworkTypes 限制候选网络类型，如 udp4 / udp6 / tcp4 / tcp6，空表示 pion 默认
+	NetworkTypes []string
+	// Interfaces 仅在这些网卡上收集候选，空表示不过滤
+	Interfaces []string
+	// LANOnly 仅收集本机 host 候选，不访问任何 STUN / TURN，适合隔离网络
+	LANOnly bool
+}
+
+// newPeerConnection 按 ICEConfig 创建 PeerConnection，Publisher / Viewer 共用
+func newPeerConnection(cfg ICEConfig) (*webrtc.PeerConnection, error) {
+	var se webrtc.SettingEngine
+
+	if len(cfg.NetworkTypes) > 0 {
+		types := make([]webrtc.NetworkType, 0, len(cfg.NetworkTypes))
+		for _, raw := range cfg.NetworkTypes {
+			t, err := webrtc.NewNetworkType(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, err
+			}
+			types = append(types, t)
+		}
+		se.SetNetworkTypes(types)
+	}
+
+	if len(cfg.Interfaces) > 0 {
+		allowed := make(map[string]bool, len(cfg.Interfaces))
+		for _, name := range cfg.Interfaces {
+			allowed[strings.TrimSpace(name)] = true
+		}
+		se.SetInterfaceFilter(func(name string) bool {
+			return allowed[name]
+		})
+	}
+
+	rtcCfg := webrtc.Configuration{}
+	if cfg.LANOnly {
+		// 不配置任何服务器即只会产生 host 候选；同时关闭 mDNS，避免在隔离网络里等待组播查询
+		se.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+	} else {
+		servers := cfg.Servers
+		if len(servers) == 0 {
+			servers = defaultICEServers
+		}
+		for _, srv := range servers {
+			rtcCfg.ICEServers = append(rtcCfg.ICEServers, webrtc.ICEServer{
+				URLs:       srv.URLs,
+				Username:   srv.Username,
+				Credential: srv.Credential,
+			})
+		}
+		if cfg.TransportPolicy != "" {
+			policy := webrtc.NewICETransportPolicy(cfg.TransportPolicy)
+			if policy.String() != cfg.TransportPolicy {
+				return nil, errors.New("未知的 ICE 传输策略: " + cfg.TransportPolicy)
+			}
+			rtcCfg.ICETransportPolicy = policy
+		}
+	}
+
+	api := webrtc.NewAPI(webrtc.WithSettingEngine(se))
+	return api.NewPeerConnection(rtcCfg)
+}
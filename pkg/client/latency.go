@@ -0,0 +1,250 @@
+package client
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// 端到端（采集到显示）延迟测量：
+//
+//  1. Publisher 定期通过 control 通道发送 ping，Viewer 回 pong，
+//     按 NTP 的方式估算两端时钟差（取最近几次中往返最短的一次，最不受排队影响）；
+//  2. 每一帧帧头带有采集时间，Viewer 显示后记录本地显示时间，定期 ack 最新一帧；
+//  3. Publisher 把显示时间换算到自己的时钟，减去采集时间得到延迟，
+//     并回送给 Viewer 供界面展示。
+
+const (
+	clockSyncInterval = 2 * time.Second
+	ackInterval       = time.Second
+	// 保留的时钟同步样本数
+	clockSamples = 8
+)
+
+// pingPayload / pongPayload 为 NTP 式对时消息，时间均为 Unix 纳秒
+type pingPayload struct {
+	Sent int64 `json:"t1"`
+}
+
+type pongPayload struct {
+	Sent     int64 `json:"t1"`
+	Received int64 `json:"t2"`
+	Replied  int64 `json:"t3"`
+}
+
+// ackPayload 为 Viewer 对最近显示的一帧的确认
+type ackPayload struct {
+	Seq       uint32 `json:"seq"`
+	Captured  int64  `json:"captured"`
+	Displayed int64  `json:"displayed"`
+}
+
+// latencyPayload 为 Publisher 回送给 Viewer 的延迟测量结果
+type latencyPayload struct {
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+type clockSample struct {
+	offset time.Duration // Viewer 时钟 - Publisher 时钟
+	rtt    time.Duration
+}
+
+// latencyTracker 记录单个 Viewer 的时钟差和延迟
+type latencyTracker struct {
+	mu      sync.Mutex
+	samples []clockSample
+	latency time.Duration
+	offset  time.Duration
+	synced  bool
+	measure bool // 是否已有延迟数据
+}
+
+// addSample 加入一次 ping/pong 结果，t4 为收到 pong 的时刻
+func (t *latencyTracker) addSample(p pongPayload, t4 int64) {
+	rtt := time.Duration((t4 - p.Sent) - (p.Replied - p.Received))
+	if rtt < 0 {
+		return
+	}
+	offset := time.Duration(((p.Received - p.Sent) + (p.Replied - t4)) / 2)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples = append(t.samples, clockSample{offset: offset, rtt: rtt})
+	if len(t.samples) > clockSamples {
+		t.samples = t.samples[len(t.samples)-clockSamples:]
+	}
+	best := t.samples[0]
+	for _, s := range t.samples[1:] {
+		if s.rtt < best.rtt {
+			best = s
+		}
+	}
+	t.offset = best.offset
+	t.synced = true
+}
+
+// addAck 根据 Viewer 的确认更新延迟，时钟尚未同步时返回 false
+func (t *latencyTracker) addAck(a ackPayload) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.synced || a.Captured == 0 {
+		return 0, false
+	}
+	sample := time.Duration(a.Displayed-a.Captured) - t.offset
+	if sample < 0 {
+		// 时钟差估算误差大于实际延迟时可能出现负值，按 0 处理
+		sample = 0
+	}
+	if t.measure {
+		t.latency = ewma(t.latency, sample)
+	} else {
+		t.latency = sample
+		t.measure = true
+	}
+	return t.latency, true
+}
+
+func (t *latencyTracker) snapshot() (latency, offset time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.latency, t.offset
+}
+
+// clockSyncLoop 定期向所有 Viewer 发送 ping
+func (s *Publisher) clockSyncLoop() {
+	ticker := time.NewTicker(clockSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			for peerID, peer := range s.peers {
+				ping := pingPayload{Sent: time.Now().UnixNano()}
+				if err := sendControl(peer.ctrl, controlTypePing, ping); err != nil {
+					log.Println("send ping to", peerID, "failed:", err)
+				}
+			}
+			s.mu.RUnlock()
+		}
+	}
+}
+
+// handlePong 与 handleAck 由 Publisher 的 control 消息分发调用
+func (s *Publisher) handlePong(peerID string, data json.RawMessage) {
+	now := time.Now().UnixNano()
+	var p pongPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("publisher parse pong from", peerID, "error:", err)
+		return
+	}
+	s.mu.RLock()
+	peer, ok := s.peers[peerID]
+	s.mu.RUnlock()
+	if ok {
+		peer.latency.addSample(p, now)
+	}
+}
+
+func (s *Publisher) handleAck(peerID string, data json.RawMessage) {
+	var a ackPayload
+	if err := json.Unmarshal(data, &a); err != nil {
+		log.Println("publisher parse ack from", peerID, "error:", err)
+		return
+	}
+	s.mu.RLock()
+	peer, ok := s.peers[peerID]
+	var ctrl *webrtc.DataChannel
+	if ok {
+		ctrl = peer.ctrl
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	latency, ok := peer.latency.addAck(a)
+	if !ok {
+		return
+	}
+	if err := sendControl(ctrl, controlTypeLatency, latencyPayload{LatencyMs: float64(latency) / float64(time.Millisecond)}); err != nil {
+		log.Println("send latency to", peerID, "failed:", err)
+	}
+}
+
+// -------------------- Viewer 侧 --------------------
+
+// displayedFrame 记录 Viewer 最近显示的一帧
+type displayedFrame struct {
+	seq       uint32
+	captured  int64
+	displayed int64
+}
+
+// markDisplayed 在帧显示到界面后调用
+func (s *viewerSession) markDisplayed(h frameHeader) {
+	if h.Captured == 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	s.mu.Lock()
+	s.lastFrame = displayedFrame{seq: h.Seq, captured: h.Captured, displayed: now}
+	s.mu.Unlock()
+}
+
+// ackLoop 定期把最近显示的一帧确认给 Publisher
+func (s *viewerSession) ackLoop() {
+	ticker := time.NewTicker(ackInterval)
+	defer ticker.Stop()
+	var acked uint32
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			f := s.lastFrame
+			ctrl := s.ctrl
+			s.mu.Unlock()
+			if f.captured == 0 || f.seq == acked {
+				continue
+			}
+			acked = f.seq
+			ack := ackPayload{Seq: f.seq, Captured: f.captured, Displayed: f.displayed}
+			if err := sendControl(ctrl, controlTypeAck, ack); err != nil {
+				log.Println("viewer send ack error:", err)
+			}
+		}
+	}
+}
+
+// handlePing 立即回复 pong，附带本端收到与回复的时间
+func (s *viewerSession) handlePing(data json.RawMessage) {
+	received := time.Now().UnixNano()
+	var p pingPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("viewer parse ping error:", err)
+		return
+	}
+	s.mu.Lock()
+	ctrl := s.ctrl
+	s.mu.Unlock()
+	pong := pongPayload{Sent: p.Sent, Received: received, Replied: time.Now().UnixNano()}
+	if err := sendControl(ctrl, controlTypePong, pong); err != nil {
+		log.Println("viewer send pong error:", err)
+	}
+}
+
+func (s *viewerSession) handleLatency(data json.RawMessage) {
+	var l latencyPayload
+	if err := json.Unmarshal(data, &l); err != nil {
+		log.Println("viewer parse latency error:", err)
+		return
+	}
+	if s.onLatency != nil {
+		s.onLatency(time.Duration(l.LatencyMs * float64(time.Millisecond)))
+	}
+}
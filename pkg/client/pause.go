@@ -0,0 +1,107 @@
+package client
+
+import (
+	"image"
+	"log"
+	"time"
+
+	"snap-screen/pkg/codec"
+)
+
+// 暂停期间重复发送占位帧的间隔，保证中途加入的 Viewer 也能看到占位画面
+const placeholderResendInterval = time.Second
+
+// statePayload 是 state 控制消息的内容
+type statePayload struct {
+	State StreamState `json:"state"`
+}
+
+// Pause 暂停分享：Viewer 连接保持不变，但不再采集屏幕，改为发送占位画面
+func (s *Publisher) Pause() {
+	s.pauseMu.Lock()
+	if s.paused {
+		s.pauseMu.Unlock()
+		return
+	}
+	s.paused = true
+	s.placeholder = nil
+	s.pauseMu.Unlock()
+
+	s.broadcastState(StreamStatePaused)
+	s.updateStatus(PublisherStatusPaused, "已暂停分享，Viewer 看到的是占位画面")
+}
+
+// Resume 恢复分享
+func (s *Publisher) Resume() {
+	s.pauseMu.Lock()
+	if !s.paused {
+		s.pauseMu.Unlock()
+		return
+	}
+	s.paused = false
+	s.placeholder = nil
+	s.pauseMu.Unlock()
+
+	s.broadcastState(StreamStateLive)
+	s.updateStatus(PublisherStatusRunning, "已恢复分享")
+}
+
+// Paused 返回当前是否处于暂停状态
+func (s *Publisher) Paused() bool {
+	return s.isPaused()
+}
+
+func (s *Publisher) isPaused() bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	return s.paused
+}
+
+func (s *Publisher) streamState() StreamState {
+	if s.isPaused() {
+		return StreamStatePaused
+	}
+	return StreamStateLive
+}
+
+func (s *Publisher) setLastFrame(frame *image.RGBA) {
+	s.pauseMu.Lock()
+	s.lastFrame = frame
+	s.pauseMu.Unlock()
+}
+
+// sendPlaceholder 在暂停期间由 captureLoop 调用，占位帧只生成一次并按间隔重发
+func (s *Publisher) sendPlaceholder() {
+	s.pauseMu.Lock()
+	if s.placeholder != nil && time.Since(s.placeholderSent) < placeholderResendInterval {
+		s.pauseMu.Unlock()
+		return
+	}
+	if s.placeholder == nil {
+		// 占位帧统一使用 JPEG，所有 Viewer 都能解码
+		frame := renderPlaceholder(s.cfg.Placeholder, s.lastFrame, s.cfg.Width, s.cfg.Height)
+		frames, _, err := s.encodeFrame(frame, s.nextHeader(time.Time{}), []codec.ID{codec.JPEG})
+		if err != nil {
+			s.pauseMu.Unlock()
+			log.Println("encode placeholder failed:", err)
+			return
+		}
+		s.placeholder = frames[codec.JPEG]
+	}
+	payload := s.placeholder
+	s.placeholderSent = time.Now()
+	s.pauseMu.Unlock()
+
+	s.broadcastFrame(map[frameTarget][]byte{{codec: codec.JPEG}: payload})
+}
+
+// broadcastState 通过 control 通道把推流状态通知给所有 Viewer
+func (s *Publisher) broadcastState(state StreamState) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for peerID, peer := range s.peers {
+		if err := sendControl(peer.ctrl, controlTypeState, statePayload{State: state}); err != nil {
+			log.Println("send state to", peerID, "failed:", err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync"
+	"testing"
+	"time"
+
+	"snap-screen/pkg/annotate"
+	"snap-screen/pkg/codec"
+	"snap-screen/pkg/source"
+)
+
+// 基准测试使用 4K 测试图案。条带和流水线的收益来自多核并行，单核机器上两者都只有调度开销，
+// 运行时可用 -cpu 1,4,8 对比：
+//
+//	go test -run '^$' -bench . -cpu 1,4,8 ./pkg/client
+const benchWidth, benchHeight = 3840, 2160
+
+var benchCodecs = []codec.ID{codec.JPEG, codec.PNG, codec.QOI}
+
+// benchPublisher 创建只用于驱动流水线各阶段的 Publisher，不连接信令；
+// width / height 为输出分辨率，0 为保持原始分辨率
+func benchPublisher(b *testing.B, id codec.ID, width, height int) *Publisher {
+	b.Helper()
+	enc, err := codec.NewEncoder(id, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(cancel)
+	return &Publisher{
+		cfg:      PublisherConfig{Width: width, Height: height},
+		board:    annotate.NewBoard(),
+		encoders: map[codec.ID]codec.Encoder{id: enc},
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// BenchmarkEncodeStrips 对比一帧 4K 画面整帧编码与拆成 maxStrips 个条带并行编码
+func BenchmarkEncodeStrips(b *testing.B) {
+	frame := source.NewTestPattern(benchWidth, benchHeight).CaptureFrame()
+	for _, id := range benchCodecs {
+		enc, err := codec.NewEncoder(id, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, n := range []int{1, maxStrips} {
+			strips := splitStrips(frame.Bounds(), n)
+			b.Run(fmt.Sprintf("%s/strips=%d", id, len(strips)), func(b *testing.B) {
+				size := 0
+				for b.Loop() {
+					parts, err := encodeStrips(enc, frame, strips)
+					if err != nil {
+						b.Fatal(err)
+					}
+					size = 0
+					for _, p := range parts {
+						size += len(p)
+					}
+				}
+				b.ReportMetric(float64(size), "bytes/frame")
+			})
+		}
+	}
+}
+
+// BenchmarkPipeline 对比采集 → 缩放/水印 → 编码逐帧串行执行与三个阶段并发执行时的吞吐量。
+// 这里各阶段之间用阻塞的队列，只衡量阶段重叠带来的收益；实际推流时下游跟不上会丢弃旧帧
+func BenchmarkPipeline(b *testing.B) {
+	for _, id := range benchCodecs {
+		targets := []frameTarget{{codec: id}}
+		for _, mode := range []string{"serial", "pipelined"} {
+			b.Run(fmt.Sprintf("%s/%s", id, mode), func(b *testing.B) {
+				s := benchPublisher(b, id, 1920, 1080)
+				src := source.NewTestPattern(benchWidth, benchHeight)
+				capture := func() *pipelineFrame {
+					return &pipelineFrame{frame: src.CaptureFrame(), targets: targets, captured: time.Now()}
+				}
+				encode := func(f *pipelineFrame) {
+					if _, _, err := s.encodeTargets(f); err != nil {
+						b.Error(err)
+					}
+				}
+
+				b.ResetTimer()
+				if mode == "serial" {
+					for range b.N {
+						f := capture()
+						s.scaleFrame(f)
+						encode(f)
+					}
+					return
+				}
+				scaleCh := make(chan *pipelineFrame, 1)
+				encodeCh := make(chan *pipelineFrame, 1)
+				var wg sync.WaitGroup
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					defer close(encodeCh)
+					for f := range scaleCh {
+						s.scaleFrame(f)
+						encodeCh <- f
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for f := range encodeCh {
+						encode(f)
+					}
+				}()
+				for range b.N {
+					scaleCh <- capture()
+				}
+				close(scaleCh)
+				wg.Wait()
+			})
+		}
+	}
+}
+
+// TestSplitStrips 检查条带覆盖整幅画面、互不重叠且高度对齐到 16 行
+func TestSplitStrips(t *testing.T) {
+	for _, tc := range []struct {
+		b    image.Rectangle
+		n    int
+		want int
+	}{
+		{image.Rect(0, 0, benchWidth, benchHeight), maxStrips, maxStrips},
+		{image.Rect(0, 0, 1280, 720), 4, 4},
+		{image.Rect(0, 0, 1280, 200), 4, 1},
+		{image.Rect(0, 0, 640, 360), 1, 1},
+	} {
+		strips := splitStrips(tc.b, tc.n)
+		if len(strips) != tc.want {
+			t.Errorf("splitStrips(%v, %d) = %d 个条带, 期望 %d", tc.b, tc.n, len(strips), tc.want)
+		}
+		y := tc.b.Min.Y
+		for i, r := range strips {
+			if r.Min.Y != y || r.Dx() != tc.b.Dx() {
+				t.Errorf("splitStrips(%v, %d) 第 %d 条 %v 与上一条不相接", tc.b, tc.n, i, r)
+			}
+			if i < len(strips)-1 && r.Dy()%16 != 0 {
+				t.Errorf("splitStrips(%v, %d) 第 %d 条高度 %d 未对齐到 16 行", tc.b, tc.n, i, r.Dy())
+			}
+			y = r.Max.Y
+		}
+		if y != tc.b.Max.Y {
+			t.Errorf("splitStrips(%v, %d) 未覆盖到底部: %d", tc.b, tc.n, y)
+		}
+	}
+}
+
+// TestSnapshotThroughPipeline 检查截图请求随采集帧进入缩放阶段，替换旧帧时不会丢失，
+// 得到的是未缩放的画面，推流画面仍按配置缩放
+func TestSnapshotThroughPipeline(t *testing.T) {
+	s := &Publisher{cfg: PublisherConfig{Width: 640, Height: 360}, board: annotate.NewBoard()}
+	src := source.NewTestPattern(1280, 720)
+	result := make(chan snapshotResult, 1)
+	ch := make(chan *pipelineFrame, 1)
+	s.offerLatest(ch, &pipelineFrame{frame: src.CaptureFrame(), snapshot: result})
+	s.offerLatest(ch, &pipelineFrame{frame: src.CaptureFrame()})
+
+	f := <-ch
+	s.scaleFrame(f)
+	if got := f.frame.Bounds(); got != image.Rect(0, 0, 640, 360) {
+		t.Fatalf("推流画面 %v, 期望 640x360", got)
+	}
+	if f.snapshot != nil {
+		t.Fatal("缩放后截图请求未清除")
+	}
+	select {
+	case r := <-result:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if got := r.frame.Bounds(); got != image.Rect(0, 0, 1280, 720) {
+			t.Fatalf("截图 %v, 期望 1280x720", got)
+		}
+	default:
+		t.Fatal("替换旧帧后截图请求丢失")
+	}
+}
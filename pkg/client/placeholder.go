@@ -0,0 +1,98 @@
+package client
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"snap-screen/pkg/screen"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// PlaceholderMode 决定暂停分享期间发送给 Viewer 的占位画面
+type PlaceholderMode string
+
+const (
+	PlaceholderSlate PlaceholderMode = "slate" // 深色底 + 提示文字（默认）
+	PlaceholderBlur  PlaceholderMode = "blur"  // 模糊处理后的最后一帧
+	PlaceholderImage PlaceholderMode = "image" // 自定义图片
+)
+
+// PlaceholderConfig 配置暂停时的占位画面
+type PlaceholderConfig struct {
+	Mode PlaceholderMode
+	// Text 为叠加在占位画面上的提示文字，内置字体仅支持 ASCII
+	Text string
+	// Image 在 PlaceholderImage 模式下使用，会缩放到输出分辨率
+	Image image.Image
+}
+
+const defaultPlaceholderText = "SHARING PAUSED"
+
+// 尚未采集过任何帧且未指定输出分辨率时的占位画面尺寸
+const (
+	defaultPlaceholderWidth  = 1280
+	defaultPlaceholderHeight = 720
+)
+
+var slateColor = color.RGBA{R: 0x20, G: 0x22, B: 0x28, A: 0xff}
+
+// renderPlaceholder 生成占位帧；last 为暂停前最后一帧（可能为 nil）
+func renderPlaceholder(cfg PlaceholderConfig, last *image.RGBA, width, height int) *image.RGBA {
+	if last != nil {
+		width, height = last.Bounds().Dx(), last.Bounds().Dy()
+	}
+	if width <= 0 || height <= 0 {
+		width, height = defaultPlaceholderWidth, defaultPlaceholderHeight
+	}
+	text := cfg.Text
+	if text == "" {
+		text = defaultPlaceholderText
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	switch {
+	case cfg.Mode == PlaceholderImage && cfg.Image != nil:
+		xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), cfg.Image, cfg.Image.Bounds(), xdraw.Src, nil)
+		return dst
+	case cfg.Mode == PlaceholderBlur && last != nil:
+		// 先大幅缩小再放大回原尺寸，得到足够模糊、无法辨认细节的画面
+		small := screen.Scale(last, max(width/32, 1), max(height/32, 1))
+		xdraw.BiLinear.Scale(dst, dst.Bounds(), small, small.Bounds(), xdraw.Src, nil)
+		// 压暗一层，让提示文字更醒目
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.RGBA{A: 0x80}), image.Point{}, draw.Over)
+	default:
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(slateColor), image.Point{}, draw.Src)
+	}
+	drawCenteredText(dst, text)
+	return dst
+}
+
+// drawCenteredText 用内置点阵字体绘制文字后放大，使其宽度约占画面的一半
+func drawCenteredText(dst *image.RGBA, text string) {
+	face := basicfont.Face7x13
+	tw := font.MeasureString(face, text).Ceil()
+	th := face.Metrics().Height.Ceil()
+	if tw <= 0 || th <= 0 {
+		return
+	}
+	label := image.NewRGBA(image.Rect(0, 0, tw, th))
+	d := font.Drawer{
+		Dst:  label,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.P(0, face.Metrics().Ascent.Ceil()),
+	}
+	d.DrawString(text)
+
+	b := dst.Bounds()
+	scale := max(b.Dx()/2/tw, 1)
+	w, h := tw*scale, th*scale
+	x := b.Min.X + (b.Dx()-w)/2
+	y := b.Min.Y + (b.Dy()-h)/2
+	xdraw.NearestNeighbor.Scale(dst, image.Rect(x, y, x+w, y+h), label, label.Bounds(), xdraw.Over, nil)
+}
@@ -1,14 +1,17 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"image"
-	"image/jpeg"
 	"log"
-	"snap-screen/pkg/screen"
+	"slices"
+	"snap-screen/pkg/annotate"
+	"snap-screen/pkg/codec"
+	"snap-screen/pkg/overlay"
+	"snap-screen/pkg/source"
+	"snap-screen/pkg/transfer"
 	"sync"
 	"time"
 
@@ -16,15 +19,58 @@ import (
 	"github.com/pion/webrtc/v4"
 )
 
+const (
+	// Offer 之前到达的 ICE 候选的缓存上限，见 bufferEarlyICE
+	maxEarlyICEPeers      = 32
+	maxEarlyICECandidates = 64
+	earlyICETTL           = 30 * time.Second
+)
+
+// earlyICE 为某个 peer 在 Offer 之前发来的候选
+type earlyICE struct {
+	cands []webrtc.ICECandidateInit
+	first time.Time // 收到第一个候选的时刻
+}
+
 type peerSession struct {
-	pc *webrtc.PeerConnection
-	dc *webrtc.DataChannel
+	pc    *webrtc.PeerConnection
+	dc    *webrtc.DataChannel
+	ctrl  *webrtc.DataChannel // control 通道，用于状态通知等 JSON 消息
+	input *webrtc.DataChannel // 远程控制的键鼠事件通道
+	files *transfer.Manager   // files 通道上的文件收发
+
+	// 远端 SDP 设置前收到的 ICE 候选先缓存，之后一次性加入
+	remoteSet   bool
+	pendingICEs []webrtc.ICECandidateInit
+
+	// codec 为与该 Viewer 协商出的帧编码，收到 hello 之前为 JPEG
+	codec codec.ID
+	// zoom 为该 Viewer 请求放大的区域，零值为完整画面，见 zoom.go
+	zoom ZoomRegion
+
+	sendErrors uint64 // 原子计数
+	dropped    uint64 // 因发送缓冲积压而跳过的帧数，原子计数
+
+	latency latencyTracker
+
+	// lastSnapshot 为最近一次接受该 Viewer 截图请求的时刻，用于限频
+	lastSnapshot time.Time
+	// recording 为该 Viewer 是否告知正在录制，见 recording.go
+	recording bool
 }
 
-type publisherSession struct {
+// Publisher 是一次独立的推流会话，同一进程内可以同时运行多个（例如两块屏幕各推一路流）
+type Publisher struct {
 	streamID string
-	capture  *screen.Capture
+	source   source.FrameSource
 	cfg      PublisherConfig
+	overlay  *overlay.Renderer // 未配置水印时为 nil
+	// board 为权威的标注副本；annotator 只在开启烧录时创建
+	board     *annotate.Board
+	annotator *annotate.Renderer
+	// encoders 包含 JPEG 以及配置的首选编码，启动后只读
+	encoders map[codec.ID]codec.Encoder
+	frameSeq uint32 // 原子递增的帧序号
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -34,66 +80,176 @@ type publisherSession struct {
 	mu    sync.RWMutex
 	ws    *websocket.Conn
 	peers map[string]*peerSession
+	// 早于 Offer 到达的 ICE 候选（trickle ICE 下 Viewer 可能先发候选），按 peerID 暂存，见 bufferEarlyICE
+	earlyICEs map[string]*earlyICE
+
+	// gorilla/websocket 不允许并发写，ICE 回调与主流程共用这把锁
+	writeMu sync.Mutex
+
+	// 最近一次状态，供 Status() 查询
+	statusMu     sync.Mutex
+	status       PublisherStatus
+	statusDetail string
+
+	meter pipelineMeter
+
+	// 远程控制状态，见 remote.go
+	remote remoteControl
+
+	// 暂停状态及占位帧缓存，见 pause.go
+	pauseMu         sync.Mutex
+	paused          bool
+	lastFrame       *image.RGBA
+	placeholder     []byte
+	placeholderSent time.Time
+
+	// snapshotMu 保证同一时刻只处理一个截图请求；snapshotReq 把请求交给采集阶段，见 snapshot.go
+	snapshotMu  sync.Mutex
+	snapshotReq chan chan snapshotResult
+
+	// 本端录制，见 recording.go
+	recMu    sync.Mutex
+	recorder *publisherRecorder
+
+	stopOnce sync.Once
+	// registered 在信令服务器确认注册后关闭
+	registered chan struct{}
+	done       chan struct{}
+	err        error // 导致会话异常结束的错误，手动 Stop 时为 nil
 }
 
+// 通过 StartPublisher 启动、尚未停止的会话，供兼容接口 StopPublisher 使用
 var (
-	publisherMu     sync.Mutex
-	activePublisher *publisherSession
+	publisherMu      sync.Mutex
+	activePublishers = make(map[*Publisher]struct{})
 )
 
-// StartPublisher 初始化 WebRTC 推流（Publisher 端）
-func StartPublisher(streamID string, capture *screen.Capture, cfg PublisherConfig, statusFn func(PublisherStatus, string)) error {
+// StartPublisher 初始化 WebRTC 推流（Publisher 端），返回可独立控制的会话句柄。
+// src 可以是 *screen.Capture，也可以是测试图案、幻灯片等任意 FrameSource。
+func StartPublisher(streamID string, src source.FrameSource, cfg PublisherConfig, statusFn func(PublisherStatus, string)) (*Publisher, error) {
 	if streamID == "" {
-		return errors.New("stream id 不能为空")
+		return nil, errors.New("stream id 不能为空")
 	}
-	if capture == nil {
-		return errors.New("capture 不能为空")
+	if src == nil {
+		return nil, errors.New("frame source 不能为空")
 	}
 	normalizeConfig(&cfg)
 
-	publisherMu.Lock()
-	defer publisherMu.Unlock()
-	if activePublisher != nil {
-		return errors.New("已有推流任务在运行")
+	var ov *overlay.Renderer
+	if cfg.Overlay.Enabled() {
+		if cfg.Overlay.Name == "" {
+			cfg.Overlay.Name = streamID
+		}
+		r, err := overlay.NewRenderer(cfg.Overlay)
+		if err != nil {
+			return nil, err
+		}
+		ov = r
+	}
+
+	encoders := make(map[codec.ID]codec.Encoder, 2)
+	for _, id := range []codec.ID{codec.JPEG, cfg.Codec} {
+		enc, err := codec.NewEncoder(id, cfg.JPEGQuality)
+		if err != nil {
+			return nil, err
+		}
+		encoders[id] = enc
+	}
+
+	var annotator *annotate.Renderer
+	if cfg.BurnAnnotations {
+		r, err := annotate.NewRenderer()
+		if err != nil {
+			return nil, err
+		}
+		annotator = r
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	s := &publisherSession{
-		streamID: streamID,
-		capture:  capture,
-		cfg:      cfg,
-		ctx:      ctx,
-		cancel:   cancel,
-		statusFn: statusFn,
-		peers:    make(map[string]*peerSession),
+	s := &Publisher{
+		streamID:    streamID,
+		source:      src,
+		cfg:         cfg,
+		overlay:     ov,
+		board:       annotate.NewBoard(),
+		annotator:   annotator,
+		encoders:    encoders,
+		ctx:         ctx,
+		cancel:      cancel,
+		statusFn:    statusFn,
+		peers:       make(map[string]*peerSession),
+		earlyICEs:   make(map[string]*earlyICE),
+		snapshotReq: make(chan chan snapshotResult, 1),
+		status:      PublisherStatusDisconnected,
+		registered:  make(chan struct{}),
+		done:        make(chan struct{}),
 	}
 
 	if err := s.connectAndRegister(); err != nil {
 		cancel()
-		return err
+		return nil, err
 	}
 
-	activePublisher = s
+	publisherMu.Lock()
+	activePublishers[s] = struct{}{}
+	publisherMu.Unlock()
+
 	s.updateStatus(PublisherStatusConnected, "信令连接成功")
 	go s.signalReadLoop()
-	go s.captureLoop()
-	return nil
+	go s.runPipeline()
+	go s.clockSyncLoop()
+	return s, nil
 }
 
-// StopPublisher 停止推流
+// StopPublisher 停止所有通过 StartPublisher 启动的推流会话（兼容旧的单会话接口）
 func StopPublisher() {
 	publisherMu.Lock()
-	s := activePublisher
-	activePublisher = nil
+	sessions := make([]*Publisher, 0, len(activePublishers))
+	for s := range activePublishers {
+		sessions = append(sessions, s)
+	}
 	publisherMu.Unlock()
 
-	if s == nil {
-		return
+	for _, s := range sessions {
+		s.Stop()
 	}
-	s.stop()
 }
 
-func (s *publisherSession) connectAndRegister() error {
+// StreamID 返回本会话注册的流 ID
+func (s *Publisher) StreamID() string {
+	return s.streamID
+}
+
+// Stop 停止本会话并释放所有 Viewer 连接，可重复调用
+func (s *Publisher) Stop() {
+	s.stop(nil)
+}
+
+// Status 返回会话最近一次上报的状态及说明文字
+func (s *Publisher) Status() (PublisherStatus, string) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	return s.status, s.statusDetail
+}
+
+// Wait 阻塞直到会话结束；因信令断开等异常结束时返回对应错误
+func (s *Publisher) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Done 返回会话结束时关闭的通道，便于与 select 配合使用
+func (s *Publisher) Done() <-chan struct{} {
+	return s.done
+}
+
+// Registered 返回信令服务器确认注册后关闭的通道。注册被拒绝（例如 stream ID 已被占用）时
+// 会话直接结束，Done 关闭而该通道不会关闭
+func (s *Publisher) Registered() <-chan struct{} {
+	return s.registered
+}
+
+func (s *Publisher) connectAndRegister() error {
 	ws, _, err := websocket.DefaultDialer.Dial(s.cfg.SignalURL, nil)
 	if err != nil {
 		s.updateStatus(PublisherStatusError, "连接信令失败: "+err.Error())
@@ -114,7 +270,8 @@ func (s *publisherSession) connectAndRegister() error {
 	return nil
 }
 
-func (s *publisherSession) signalReadLoop() {
+func (s *Publisher) signalReadLoop() {
+	registered := false
 	for {
 		select {
 		case <-s.ctx.Done():
@@ -128,7 +285,7 @@ func (s *publisherSession) signalReadLoop() {
 				return
 			}
 			s.updateStatus(PublisherStatusError, "信令读取失败: "+err.Error())
-			s.stop()
+			s.stop(err)
 			return
 		}
 
@@ -140,8 +297,17 @@ func (s *publisherSession) signalReadLoop() {
 
 		switch msg.Type {
 		case "success":
+			if !registered {
+				registered = true
+				close(s.registered)
+			}
 			s.updateStatus(PublisherStatusRunning, "已注册 stream，等待 Viewer 订阅")
 		case "error":
+			if !registered {
+				// 注册之前的错误都是对 register 的回复，未注册的会话不会有 Viewer 连上来
+				s.stop(errors.New("注册 stream 失败: " + msg.Error))
+				return
+			}
 			s.updateStatus(PublisherStatusError, msg.Error)
 		case "offer":
 			if err := s.handleOffer(msg); err != nil {
@@ -155,57 +321,63 @@ func (s *publisherSession) signalReadLoop() {
 	}
 }
 
-func (s *publisherSession) captureLoop() {
-	interval := time.Second / time.Duration(s.cfg.FrameRate)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-s.ctx.Done():
-			return
-		case <-ticker.C:
-			// 没有任何订阅者时不做采集和编码，节省 CPU
-			s.mu.RLock()
-			hasPeers := len(s.peers) > 0
-			s.mu.RUnlock()
-			if !hasPeers {
-				continue
-			}
-			frame := s.capturedFrame()
-			if frame == nil {
-				continue
-			}
-			payload, err := encodeJPEG(frame)
-			if err != nil {
-				s.updateStatus(PublisherStatusError, "帧编码失败: "+err.Error())
-				continue
-			}
-			s.broadcastFrame(payload)
-		}
+// Viewers 返回当前连接的所有 Viewer 的 peerID（按字典序）
+func (s *Publisher) Viewers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.peers))
+	for id := range s.peers {
+		ids = append(ids, id)
 	}
+	slices.Sort(ids)
+	return ids
+}
+
+// frameTarget 为需要编码的一种输出：显示区域与帧编码的组合
+type frameTarget struct {
+	zoom  ZoomRegion
+	codec codec.ID
 }
 
-func (s *publisherSession) capturedFrame() *image.RGBA {
-	if s.cfg.Width > 0 && s.cfg.Height > 0 {
-		return s.capture.CaptureFrameSized(s.cfg.Width, s.cfg.Height)
+// peerTargets 返回当前所有 Viewer 需要的输出（去重），没有 Viewer 时为空
+func (s *Publisher) peerTargets() []frameTarget {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var targets []frameTarget
+	for _, peer := range s.peers {
+		t := frameTarget{zoom: peer.zoom, codec: peer.codec}
+		if !slices.Contains(targets, t) {
+			targets = append(targets, t)
+		}
 	}
-	return s.capture.CaptureFrame()
+	return targets
 }
 
-func (s *publisherSession) broadcastFrame(payload []byte) {
+// broadcastFrame 按每个 Viewer 的区域和协商的编码发送对应的帧。缺少该编码时退回 JPEG；
+// 采集后才改变区域的 Viewer 本帧退回完整画面，占位帧也只有完整画面
+func (s *Publisher) broadcastFrame(frames map[frameTarget][]byte) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	for _, peer := range s.peers {
-		if peer.dc != nil && peer.dc.ReadyState() == webrtc.DataChannelStateOpen {
-			if err := peer.dc.Send(payload); err != nil {
-				log.Println("send frame failed:", err)
+		var payload []byte
+		for _, t := range []frameTarget{
+			{peer.zoom, peer.codec}, {peer.zoom, codec.JPEG},
+			{ZoomRegion{}, peer.codec}, {ZoomRegion{}, codec.JPEG},
+		} {
+			if payload = frames[t]; payload != nil {
+				break
 			}
 		}
+		if payload == nil {
+			continue
+		}
+		if peer.dc != nil && peer.dc.ReadyState() == webrtc.DataChannelStateOpen {
+			s.sendFrame(peer, payload)
+		}
 	}
 }
 
-func (s *publisherSession) handleOffer(msg signalMessage) error {
+func (s *Publisher) handleOffer(msg signalMessage) error {
 	if msg.PeerID == "" {
 		return errors.New("offer 缺少 peer_id")
 	}
@@ -215,17 +387,42 @@ func (s *publisherSession) handleOffer(msg signalMessage) error {
 		return err
 	}
 
-	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{URLs: []string{"stun:stun.l.google.com:19302"}},
-		},
-	})
+	pc, err := newPeerConnection(s.cfg.ICE)
 	if err != nil {
 		return err
 	}
 
+	// 提前登记 peer，后续到达的 ICE 候选可以先缓存在 peerSession 上
+	ps := &peerSession{pc: pc, codec: codec.JPEG}
+	s.mu.Lock()
+	s.peers[msg.PeerID] = ps
+	s.mu.Unlock()
+
 	// Answer 端不主动创建 DataChannel，而是等待 Viewer 创建的通道协商完成后回调
 	pc.OnDataChannel(func(dc *webrtc.DataChannel) {
+		switch dc.Label() {
+		case controlChannelLabel:
+			s.mu.Lock()
+			ps.ctrl = dc
+			s.mu.Unlock()
+			s.handleControlChannel(msg.PeerID, dc)
+			return
+		case inputChannelLabel:
+			s.mu.Lock()
+			ps.input = dc
+			s.mu.Unlock()
+			dc.OnMessage(func(m webrtc.DataChannelMessage) {
+				s.handleInput(msg.PeerID, m.Data)
+			})
+			return
+		case fileChannelLabel:
+			files := s.newFileManager(msg.PeerID, dc)
+			s.mu.Lock()
+			ps.files = files
+			s.mu.Unlock()
+			return
+		}
+
 		s.updateStatus(PublisherStatusRunning, "Viewer DataChannel 已建立: "+msg.PeerID)
 		dc.OnClose(func() {
 			s.removePeer(msg.PeerID)
@@ -233,11 +430,6 @@ func (s *publisherSession) handleOffer(msg signalMessage) error {
 		})
 
 		s.mu.Lock()
-		ps, ok := s.peers[msg.PeerID]
-		if !ok {
-			ps = &peerSession{pc: pc}
-			s.peers[msg.PeerID] = ps
-		}
 		ps.dc = dc
 		s.mu.Unlock()
 	})
@@ -263,31 +455,31 @@ func (s *publisherSession) handleOffer(msg signalMessage) error {
 		}
 	})
 	if err := pc.SetRemoteDescription(remote); err != nil {
-		pc.Close()
+		s.removePeer(msg.PeerID)
 		return err
 	}
+	s.flushPendingICE(msg.PeerID, ps)
+
 	answer, err := pc.CreateAnswer(nil)
 	if err != nil {
-		pc.Close()
+		s.removePeer(msg.PeerID)
 		return err
 	}
 	if err := pc.SetLocalDescription(answer); err != nil {
-		pc.Close()
+		s.removePeer(msg.PeerID)
 		return err
 	}
-	// 等待 ICE 收集完成，确保 SDP 中包含 ice-ufrag 等字段
-	gatherComplete := webrtc.GatheringCompletePromise(pc)
-	<-gatherComplete
 
+	// trickle ICE：不等待候选收集完成，立即回 Answer，候选通过 OnICECandidate 陆续发送
 	local := pc.LocalDescription()
 	if local == nil {
-		pc.Close()
+		s.removePeer(msg.PeerID)
 		return errors.New("本地 SDP 为空")
 	}
 
 	answerBytes, err := json.Marshal(local)
 	if err != nil {
-		pc.Close()
+		s.removePeer(msg.PeerID)
 		return err
 	}
 	if err := s.writeSignal(signalMessage{
@@ -296,95 +488,200 @@ func (s *publisherSession) handleOffer(msg signalMessage) error {
 		PeerID:   msg.PeerID,
 		Data:     answerBytes,
 	}); err != nil {
-		pc.Close()
+		s.removePeer(msg.PeerID)
 		return err
 	}
+	return nil
+}
 
+// flushPendingICE 在远端 SDP 设置完成后，把提前到达的候选一次性加入
+func (s *Publisher) flushPendingICE(peerID string, ps *peerSession) {
 	s.mu.Lock()
-	if _, ok := s.peers[msg.PeerID]; !ok {
-		s.peers[msg.PeerID] = &peerSession{pc: pc}
+	defer s.mu.Unlock()
+	ps.remoteSet = true
+	var pending []webrtc.ICECandidateInit
+	if e, ok := s.earlyICEs[peerID]; ok && time.Since(e.first) <= earlyICETTL {
+		pending = e.cands
+	}
+	pending = append(pending, ps.pendingICEs...)
+	delete(s.earlyICEs, peerID)
+	ps.pendingICEs = nil
+	for _, cand := range pending {
+		if err := ps.pc.AddICECandidate(cand); err != nil {
+			log.Println("publisher flush buffered ICE error:", err)
+		}
 	}
-	s.mu.Unlock()
-	return nil
 }
 
-func (s *publisherSession) handleRemoteICE(msg signalMessage) error {
-	s.mu.RLock()
-	peer, ok := s.peers[msg.PeerID]
-	s.mu.RUnlock()
-	if !ok || peer.pc == nil {
-		return nil
-	}
+func (s *Publisher) handleRemoteICE(msg signalMessage) error {
 	var cand webrtc.ICECandidateInit
 	if err := json.Unmarshal(msg.Data, &cand); err != nil {
 		return err
 	}
+
+	s.mu.Lock()
+	peer, ok := s.peers[msg.PeerID]
+	if !ok {
+		// Offer 还没到，先按 peerID 缓存
+		s.bufferEarlyICE(msg.PeerID, cand)
+		s.mu.Unlock()
+		return nil
+	}
+	if !peer.remoteSet {
+		peer.pendingICEs = append(peer.pendingICEs, cand)
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
 	return peer.pc.AddICECandidate(cand)
 }
 
-func (s *publisherSession) removePeer(peerID string) {
+// bufferEarlyICE 暂存 Offer 之前到达的候选，调用方持有 s.mu。
+// 只发候选、一直不发 Offer 的 peer 不能让缓存无限增长：超过 earlyICETTL 的条目被丢弃，
+// 每个 peer 最多缓存 maxEarlyICECandidates 个候选，peer 数达到 maxEarlyICEPeers 时丢弃最早的一个
+func (s *Publisher) bufferEarlyICE(peerID string, cand webrtc.ICECandidateInit) {
+	now := time.Now()
+	for id, e := range s.earlyICEs {
+		if now.Sub(e.first) > earlyICETTL {
+			delete(s.earlyICEs, id)
+		}
+	}
+	e, ok := s.earlyICEs[peerID]
+	if !ok {
+		if len(s.earlyICEs) >= maxEarlyICEPeers {
+			oldest := ""
+			for id, c := range s.earlyICEs {
+				if oldest == "" || c.first.Before(s.earlyICEs[oldest].first) {
+					oldest = id
+				}
+			}
+			log.Println("publisher drop early ICE candidates of", oldest, ": too many pending peers")
+			delete(s.earlyICEs, oldest)
+		}
+		e = &earlyICE{first: now}
+		s.earlyICEs[peerID] = e
+	}
+	if len(e.cands) >= maxEarlyICECandidates {
+		log.Println("publisher drop early ICE candidate of", peerID, ": too many candidates")
+		return
+	}
+	e.cands = append(e.cands, cand)
+}
+
+func (s *Publisher) removePeer(peerID string) {
+	// 控制者断开时抬起其按下的键，避免按键卡住
+	if s.Controller() == peerID {
+		s.releaseControl()
+	}
+
+	// 进行中的传输在释放锁之后结束，避免进度回调中再次调用 Publisher 时死锁
+	var files *transfer.Manager
+	defer func() {
+		if files != nil {
+			files.Close()
+		}
+	}()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	delete(s.earlyICEs, peerID)
 	peer, ok := s.peers[peerID]
 	if !ok {
 		return
 	}
 	delete(s.peers, peerID)
+	files = peer.files
 	if peer.dc != nil {
 		peer.dc.Close()
 	}
+	if peer.ctrl != nil {
+		peer.ctrl.Close()
+	}
+	if peer.input != nil {
+		peer.input.Close()
+	}
 	if peer.pc != nil {
 		peer.pc.Close()
 	}
 }
 
-func (s *publisherSession) stop() {
+func (s *Publisher) stop(cause error) {
+	s.stopOnce.Do(func() {
+		s.shutdown(cause)
+	})
+}
+
+func (s *Publisher) shutdown(cause error) {
 	s.cancel()
+	s.releaseControl()
 	_ = s.writeSignal(signalMessage{
 		Type:     "unregister",
 		StreamID: s.streamID,
 	})
 
+	// 与 removePeer 相同，进行中的传输在释放锁之后结束
+	var files []*transfer.Manager
 	s.mu.Lock()
 	for peerID, peer := range s.peers {
+		if peer.files != nil {
+			files = append(files, peer.files)
+		}
 		if peer.dc != nil {
 			peer.dc.Close()
 		}
+		if peer.ctrl != nil {
+			peer.ctrl.Close()
+		}
+		if peer.input != nil {
+			peer.input.Close()
+		}
 		if peer.pc != nil {
 			peer.pc.Close()
 		}
 		delete(s.peers, peerID)
 	}
+	s.earlyICEs = make(map[string]*earlyICE)
 	if s.ws != nil {
 		s.ws.Close()
 		s.ws = nil
 	}
 	s.mu.Unlock()
+	for _, m := range files {
+		m.Close()
+	}
 
-	s.updateStatus(PublisherStatusStopped, "推流已停止")
+	publisherMu.Lock()
+	delete(activePublishers, s)
+	publisherMu.Unlock()
+
+	if cause != nil {
+		s.updateStatus(PublisherStatusError, "推流异常结束: "+cause.Error())
+	} else {
+		s.updateStatus(PublisherStatusStopped, "推流已停止")
+	}
+	s.err = cause
+	close(s.done)
 }
 
-func (s *publisherSession) writeSignal(msg signalMessage) error {
+func (s *Publisher) writeSignal(msg signalMessage) error {
 	s.mu.RLock()
 	ws := s.ws
 	s.mu.RUnlock()
 	if ws == nil {
 		return errors.New("信令连接不存在")
 	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	return ws.WriteJSON(msg)
 }
 
-func (s *publisherSession) updateStatus(st PublisherStatus, text string) {
+func (s *Publisher) updateStatus(st PublisherStatus, text string) {
+	s.statusMu.Lock()
+	s.status = st
+	s.statusDetail = text
+	s.statusMu.Unlock()
+
 	if s.statusFn != nil {
 		s.statusFn(st, text)
 	}
 }
-
-func encodeJPEG(img image.Image) ([]byte, error) {
-	var buf bytes.Buffer
-	// 适当降低 JPEG 质量以减小单帧大小和编码开销
-	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 60}); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
@@ -0,0 +1,273 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Viewer 的连接状态机：
+//
+//	connecting → connected ⇄ stalled
+//	     ↓            ↓         ↓
+//	     └──────→ reconnecting ─┘→ connected …
+//	任意状态 → ended（调用 Stop、关闭了重连或放弃重连）
+//
+// WebRTC 连接失败、长时间没有画面、信令服务器通知 stream 被移除时，会话丢弃当前连接，
+// 按指数退避重新订阅并协商；Publisher 以相同的 stream ID 重新注册后即可自动恢复。
+// 只有信令连接断开而画面仍在传输时，只在后台重连信令服务器，不打断画面。
+
+// ViewerState 为 Viewer 的连接状态
+type ViewerState string
+
+const (
+	ViewerStateConnecting   ViewerState = "connecting"
+	ViewerStateConnected    ViewerState = "connected"
+	ViewerStateStalled      ViewerState = "stalled"
+	ViewerStateReconnecting ViewerState = "reconnecting"
+	ViewerStateEnded        ViewerState = "ended"
+)
+
+// ReconnectConfig 控制 Viewer 断线后的自动重连
+type ReconnectConfig struct {
+	// Disable 为 true 时不重连，连接断开即进入 ended
+	Disable bool
+	// MinDelay / MaxDelay 为退避的首次与最大间隔，零值为 1 秒 / 30 秒
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// GiveUpAfter 为持续重连失败多久后放弃，零值为一直重试
+	GiveUpAfter time.Duration
+}
+
+const (
+	defaultReconnectMinDelay = time.Second
+	defaultReconnectMaxDelay = 30 * time.Second
+
+	// 发出 Offer 后超过该时长仍未建立 WebRTC 连接则重连
+	viewerConnectTimeout = 20 * time.Second
+	// 超过该时长没有收到画面进入 stalled；暂停分享时 Publisher 每秒重发占位帧，不会误判
+	viewerStallTimeout = 3 * time.Second
+	// stalled 持续该时长后放弃当前连接，重新协商
+	viewerStallRenegotiate = 10 * time.Second
+	viewerWatchInterval    = time.Second
+)
+
+// lostConn 报告某次连接不可用，gen 与当前连接不符时忽略
+type lostConn struct {
+	gen int
+	err error
+}
+
+func (c *ReconnectConfig) normalize() {
+	if c.MinDelay <= 0 {
+		c.MinDelay = defaultReconnectMinDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = defaultReconnectMaxDelay
+	}
+	c.MaxDelay = max(c.MaxDelay, c.MinDelay)
+}
+
+// State 返回当前连接状态
+func (v *Viewer) State() ViewerState {
+	v.s.mu.Lock()
+	defer v.s.mu.Unlock()
+	return v.s.state
+}
+
+// current 返回 gen 是否仍是当前连接
+func (s *viewerSession) current(gen int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen == gen && s.ctx.Err() == nil
+}
+
+// setState 切换状态并通知 OnViewerState 和 Events；ended 之后不再变化。
+// 状态不变、只有 detail 变化时同样通知（例如重连倒计时）。
+func (s *viewerSession) setState(st ViewerState, detail string, cause error) {
+	s.mu.Lock()
+	if s.state == ViewerStateEnded || (s.state == st && s.stateDetail == detail) {
+		s.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if s.state != st {
+		s.stateSince = now
+	}
+	s.state, s.stateDetail = st, detail
+	if st == ViewerStateConnected {
+		// 连接恢复后重新计算退避和停滞
+		s.backoff = 0
+		s.failingSince = time.Time{}
+		s.frameAt = now
+	}
+	cb := s.onViewerState
+	s.mu.Unlock()
+
+	log.Printf("viewer %s: %s %s", s.streamID, st, detail)
+	if cb != nil {
+		cb(st, detail)
+	}
+	s.emit(ViewerEvent{Type: ViewerEventState, ViewerState: st, Detail: detail, Err: cause})
+}
+
+// fail 报告 gen 对应的连接不可用，由 superviseLoop 决定重连或结束
+func (s *viewerSession) fail(gen int, err error) {
+	if !s.current(gen) {
+		return
+	}
+	select {
+	case s.lost <- lostConn{gen: gen, err: err}:
+	default:
+		// 已有待处理的断开通知
+	}
+}
+
+// frameReceived 记录收到画面的时间，停滞或连接中收到画面时切换到 connected
+func (s *viewerSession) frameReceived() {
+	s.mu.Lock()
+	s.frameAt = time.Now()
+	st := s.state
+	s.mu.Unlock()
+	switch st {
+	case ViewerStateStalled:
+		s.setState(ViewerStateConnected, "画面已恢复", nil)
+	case ViewerStateConnecting, ViewerStateReconnecting:
+		s.setState(ViewerStateConnected, "正在接收画面", nil)
+	}
+}
+
+// superviseLoop 处理连接断开：按退避间隔重连，或在关闭重连、超过 GiveUpAfter 时结束会话
+func (s *viewerSession) superviseLoop() {
+	for {
+		var lost lostConn
+		select {
+		case <-s.ctx.Done():
+			return
+		case lost = <-s.lost:
+		}
+		if !s.current(lost.gen) {
+			continue
+		}
+		log.Println("viewer connection lost:", lost.err)
+		if s.reconnect.Disable {
+			s.end(lost.err)
+			return
+		}
+		if !s.reconnectLoop(lost.err) {
+			return
+		}
+	}
+}
+
+// reconnectLoop 丢弃当前连接并按退避间隔重连，直到发出新的 Offer；会话结束时返回 false
+func (s *viewerSession) reconnectLoop(cause error) bool {
+	for {
+		s.teardown()
+
+		s.mu.Lock()
+		now := time.Now()
+		if s.failingSince.IsZero() {
+			s.failingSince = now
+		}
+		failing := now.Sub(s.failingSince)
+		delay := max(s.backoff, s.reconnect.MinDelay)
+		s.backoff = min(delay*2, s.reconnect.MaxDelay)
+		s.mu.Unlock()
+
+		if s.reconnect.GiveUpAfter > 0 && failing >= s.reconnect.GiveUpAfter {
+			s.end(fmt.Errorf("重连 %s 仍未成功，已放弃: %w", s.reconnect.GiveUpAfter, cause))
+			return false
+		}
+		s.setState(ViewerStateReconnecting, fmt.Sprintf("%s，%d 秒后重连", describeLost(cause), int(delay.Seconds()+0.5)), cause)
+
+		select {
+		case <-s.ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		s.setState(ViewerStateReconnecting, "正在重新连接…", cause)
+		err := s.connect()
+		if err == nil {
+			return true
+		}
+		if s.ctx.Err() != nil {
+			return false
+		}
+		log.Println("viewer reconnect error:", err)
+		cause = err
+	}
+}
+
+// resignal 在画面仍在传输、只有信令连接断开时，按退避间隔重连信令服务器并重新订阅
+func (s *viewerSession) resignal(gen int) {
+	delay := s.reconnect.MinDelay
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if !s.current(gen) {
+			return
+		}
+		ws, err := s.subscribe()
+		if err == nil {
+			log.Println("viewer signaling reconnected")
+			go s.readLoop(ws, gen)
+			return
+		}
+		log.Println("viewer resubscribe error:", err)
+		delay = min(delay*2, s.reconnect.MaxDelay)
+	}
+}
+
+// watchLoop 定期检查连接超时和画面停滞
+func (s *viewerSession) watchLoop() {
+	ticker := time.NewTicker(viewerWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.mu.Lock()
+		gen, st, since := s.gen, s.state, s.stateSince
+		pcUp, started, frameAt := s.pcUp, s.connectStarted, s.frameAt
+		s.mu.Unlock()
+
+		switch {
+		case !pcUp && !started.IsZero() && time.Since(started) > viewerConnectTimeout:
+			s.fail(gen, errors.New("建立连接超时"))
+		case st == ViewerStateConnected && time.Since(frameAt) > viewerStallTimeout:
+			s.setState(ViewerStateStalled, fmt.Sprintf("超过 %d 秒没有收到画面", int(viewerStallTimeout.Seconds())), nil)
+		case st == ViewerStateStalled && time.Since(since) > viewerStallRenegotiate:
+			s.fail(gen, errors.New("画面长时间停滞"))
+		}
+	}
+}
+
+// end 以 cause 为原因结束会话
+func (s *viewerSession) end(cause error) {
+	detail := "连接已断开"
+	if cause != nil {
+		detail = cause.Error()
+	}
+	s.setState(ViewerStateEnded, detail, cause)
+	s.stop()
+}
+
+// describeLost 返回断开原因的展示文字
+func describeLost(err error) string {
+	switch {
+	case err == nil:
+		return "连接已断开"
+	case errors.Is(err, ErrStreamRemoved):
+		return "stream 已被 Publisher 注销，等待重新注册"
+	case errors.Is(err, ErrStreamNotFound):
+		return "stream 不存在，等待 Publisher 注册"
+	}
+	return err.Error()
+}
@@ -0,0 +1,176 @@
+package client
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// 录制：Viewer 把收到的画面交给录制用的 FrameSink（例如 sink.AVI），开始和结束时通过 recording
+// 控制消息告知 Publisher，Publisher 在统计中标出正在录制的 Viewer 并回调 OnViewerRecording。
+// Publisher 也可以直接录制自己的输出画面（缩放、水印、烧录标注之后，不含放大区域）。
+
+// recordingPayload 是 recording 控制消息的内容
+type recordingPayload struct {
+	Recording bool `json:"recording"`
+}
+
+// handleRecording 记录 Viewer 的录制状态并通知调用方
+func (s *Publisher) handleRecording(peerID string, data json.RawMessage) {
+	var p recordingPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("publisher parse recording from", peerID, "error:", err)
+		return
+	}
+	s.mu.Lock()
+	peer, ok := s.peers[peerID]
+	changed := ok && peer.recording != p.Recording
+	if changed {
+		peer.recording = p.Recording
+	}
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+	log.Println("viewer", peerID, "recording:", p.Recording)
+	if s.cfg.OnViewerRecording != nil {
+		s.cfg.OnViewerRecording(peerID, p.Recording)
+	}
+}
+
+// publisherRecorder 在单独的 goroutine 中把输出画面交给录制的 FrameSink，
+// 写入跟不上时丢弃未处理的旧帧，不拖慢推流
+type publisherRecorder struct {
+	sink FrameSink
+	ch   chan Frame
+	quit chan struct{}
+	done chan struct{}
+}
+
+// StartRecording 开始把输出画面交给 rec（通常是 sink.AVI），已在录制时先停止之前的录制；
+// 没有 Viewer 时同样采集编码。暂停分享期间不录制。rec 由调用方在 StopRecording 之后关闭
+func (s *Publisher) StartRecording(rec FrameSink) {
+	r := &publisherRecorder{sink: rec, ch: make(chan Frame, 1), quit: make(chan struct{}), done: make(chan struct{})}
+	s.StopRecording()
+	s.recMu.Lock()
+	s.recorder = r
+	s.recMu.Unlock()
+	go s.recordLoop(r)
+}
+
+// StopRecording 停止录制并等待正在写入的一帧完成，返回之前的 rec，未在录制时返回 nil
+func (s *Publisher) StopRecording() FrameSink {
+	s.recMu.Lock()
+	r := s.recorder
+	s.recorder = nil
+	s.recMu.Unlock()
+	if r == nil {
+		return nil
+	}
+	close(r.quit)
+	<-r.done
+	return r.sink
+}
+
+// Recording 返回 Publisher 自身是否正在录制
+func (s *Publisher) Recording() bool {
+	s.recMu.Lock()
+	defer s.recMu.Unlock()
+	return s.recorder != nil
+}
+
+// recordFrame 在编码阶段调用，把本帧放入录制队列，队列中未处理的旧帧被替换
+func (s *Publisher) recordFrame(f *pipelineFrame) {
+	s.recMu.Lock()
+	r := s.recorder
+	s.recMu.Unlock()
+	if r == nil {
+		return
+	}
+	frame := Frame{Image: f.frame, Captured: f.captured, Received: time.Now()}
+	for {
+		select {
+		case r.ch <- frame:
+			return
+		default:
+		}
+		select {
+		case <-r.ch:
+		default:
+		}
+	}
+}
+
+func (s *Publisher) recordLoop(r *publisherRecorder) {
+	defer close(r.done)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-r.quit:
+			return
+		case f := <-r.ch:
+			if err := r.sink.WriteFrame(f); err != nil {
+				log.Println("publisher record frame error:", err)
+			}
+		}
+	}
+}
+
+// -------------------- Viewer 侧 --------------------
+
+// StartRecording 开始把之后收到的每一帧交给 rec（通常是 sink.AVI），并通知 Publisher 本端正在录制，
+// 重连后自动重新通知。已在录制时替换之前的 rec。rec 由调用方在 StopRecording 之后关闭
+func (v *Viewer) StartRecording(rec FrameSink) {
+	s := v.s
+	s.mu.Lock()
+	s.recorder = rec
+	ctrl := s.ctrl
+	s.mu.Unlock()
+	s.sendRecording(ctrl, true)
+}
+
+// StopRecording 停止录制并通知 Publisher，返回之前的 rec，未在录制时返回 nil。
+// 返回时不会再有新的帧交给 rec
+func (v *Viewer) StopRecording() FrameSink {
+	s := v.s
+	s.mu.Lock()
+	rec := s.recorder
+	s.recorder = nil
+	ctrl := s.ctrl
+	s.mu.Unlock()
+	if rec == nil {
+		return nil
+	}
+	// 等待正在进行的 deliverFrame 结束
+	s.sinkMu.Lock()
+	s.sinkMu.Unlock()
+	s.sendRecording(ctrl, false)
+	return rec
+}
+
+// Recording 返回是否正在录制
+func (v *Viewer) Recording() bool {
+	v.s.mu.Lock()
+	defer v.s.mu.Unlock()
+	return v.s.recorder != nil
+}
+
+// sendRecording 通知 Publisher 录制状态，control 通道未就绪时等通道打开后由 notifyRecording 补发
+func (s *viewerSession) sendRecording(ctrl *webrtc.DataChannel, on bool) {
+	if err := sendControl(ctrl, controlTypeRecording, recordingPayload{Recording: on}); err != nil {
+		log.Println("viewer send recording error:", err)
+	}
+}
+
+// notifyRecording 在 control 通道打开时调用，正在录制时告知新连接的 Publisher
+func (s *viewerSession) notifyRecording(ctrl *webrtc.DataChannel) {
+	s.mu.Lock()
+	on := s.recorder != nil
+	s.mu.Unlock()
+	if on {
+		s.sendRecording(ctrl, true)
+	}
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"log"
+	"sync"
+
+	"snap-screen/pkg/input"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// 远程控制：Viewer 通过 control 通道申请，Publisher 明确授权后，
+// Viewer 把键鼠事件经独立的 input 通道发送过来，由 InputInjector 注入本机。
+// 同一时刻只有一个 Viewer 持有控制权，Publisher 可以随时收回。
+
+// InputEvent 是 Viewer 发送的输入事件。X / Y 为相对画面的归一化坐标（0~1），
+// Publisher 根据捕获区域换算为桌面坐标，因此与输出分辨率、Viewer 窗口大小无关。
+type InputEvent struct {
+	Type   input.EventType   `json:"type"`
+	X      float64           `json:"x,omitempty"`
+	Y      float64           `json:"y,omitempty"`
+	Button input.MouseButton `json:"button,omitempty"`
+	DX     int               `json:"dx,omitempty"`
+	DY     int               `json:"dy,omitempty"`
+	Key    string            `json:"key,omitempty"`
+}
+
+// remoteControlPayload 为 remote_control 控制消息：Viewer 发出时表示申请 / 放弃，
+// Publisher 发出时表示授权结果
+type remoteControlPayload struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// screenBounded 由支持远程控制的帧来源实现（目前为 *screen.Capture）
+type screenBounded interface {
+	ScreenBounds() image.Rectangle
+}
+
+// remoteControl 记录当前控制者以及其按下未抬起的按键，收回控制时统一抬起，避免按键卡住
+type remoteControl struct {
+	mu         sync.Mutex
+	controller string
+	buttons    map[input.MouseButton]bool
+	keys       map[string]bool
+	x, y       int
+}
+
+// GrantControl 把键鼠控制权授予指定 Viewer，之前的控制者会被收回
+func (s *Publisher) GrantControl(peerID string) error {
+	if s.cfg.Input == nil {
+		return errors.New("未配置输入注入器，无法远程控制")
+	}
+	if _, ok := s.source.(screenBounded); !ok {
+		return errors.New("当前画面来源不是屏幕，无法远程控制")
+	}
+	s.mu.RLock()
+	peer, ok := s.peers[peerID]
+	var ctrl *webrtc.DataChannel
+	if ok {
+		ctrl = peer.ctrl
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return errors.New("Viewer 不存在: " + peerID)
+	}
+	if s.Controller() == peerID {
+		return nil
+	}
+
+	s.RevokeControl()
+	s.remote.mu.Lock()
+	s.remote.controller = peerID
+	s.remote.buttons = make(map[input.MouseButton]bool)
+	s.remote.keys = make(map[string]bool)
+	s.remote.mu.Unlock()
+
+	s.updateStatus(PublisherStatusRunning, "已授予远程控制: "+peerID)
+	return sendControl(ctrl, controlTypeRemote, remoteControlPayload{Enabled: true})
+}
+
+// RevokeControl 收回当前控制者的控制权，没有控制者时什么也不做
+func (s *Publisher) RevokeControl() {
+	peerID := s.releaseControl()
+	if peerID == "" {
+		return
+	}
+	s.mu.RLock()
+	var ctrl *webrtc.DataChannel
+	if peer, ok := s.peers[peerID]; ok {
+		ctrl = peer.ctrl
+	}
+	s.mu.RUnlock()
+	if err := sendControl(ctrl, controlTypeRemote, remoteControlPayload{Enabled: false, Reason: "对方已收回控制权"}); err != nil {
+		log.Println("send revoke to", peerID, "failed:", err)
+	}
+	s.updateStatus(PublisherStatusRunning, "已收回远程控制: "+peerID)
+}
+
+// Controller 返回当前持有控制权的 Viewer，没有时为空
+func (s *Publisher) Controller() string {
+	s.remote.mu.Lock()
+	defer s.remote.mu.Unlock()
+	return s.remote.controller
+}
+
+// releaseControl 清除控制者并抬起其所有按下的键，返回原控制者
+func (s *Publisher) releaseControl() string {
+	s.remote.mu.Lock()
+	defer s.remote.mu.Unlock()
+	r := &s.remote
+	peerID := r.controller
+	if peerID == "" {
+		return ""
+	}
+	r.controller = ""
+	for key := range r.keys {
+		if err := s.cfg.Input.Inject(input.Event{Type: input.KeyUp, Key: key}); err != nil {
+			log.Println("release key failed:", err)
+		}
+	}
+	for button := range r.buttons {
+		if err := s.cfg.Input.Inject(input.Event{Type: input.MouseUp, Button: button, X: r.x, Y: r.y}); err != nil {
+			log.Println("release button failed:", err)
+		}
+	}
+	r.keys, r.buttons = nil, nil
+	return peerID
+}
+
+// handleRemoteRequest 处理 Viewer 的控制申请 / 放弃
+func (s *Publisher) handleRemoteRequest(peerID string, data json.RawMessage) {
+	var req remoteControlPayload
+	if err := json.Unmarshal(data, &req); err != nil {
+		log.Println("publisher parse remote_control from", peerID, "error:", err)
+		return
+	}
+	if !req.Enabled {
+		if s.Controller() == peerID {
+			s.RevokeControl()
+		}
+		return
+	}
+
+	reason := ""
+	switch {
+	case s.cfg.Input == nil:
+		reason = "对方未开启远程控制"
+	case s.cfg.OnControlRequest == nil:
+		reason = "对方无法处理控制申请"
+	}
+	if reason != "" {
+		s.mu.RLock()
+		var ctrl *webrtc.DataChannel
+		if peer, ok := s.peers[peerID]; ok {
+			ctrl = peer.ctrl
+		}
+		s.mu.RUnlock()
+		_ = sendControl(ctrl, controlTypeRemote, remoteControlPayload{Enabled: false, Reason: reason})
+		return
+	}
+	// 是否授权由上层（通常是弹窗询问用户）决定，同意后调用 GrantControl
+	s.cfg.OnControlRequest(peerID)
+}
+
+// handleInput 注入控制者发来的输入事件，其他 Viewer 的事件直接丢弃
+func (s *Publisher) handleInput(peerID string, data []byte) {
+	var ev InputEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		log.Println("publisher parse input from", peerID, "error:", err)
+		return
+	}
+	src, ok := s.source.(screenBounded)
+	if !ok || s.cfg.Input == nil {
+		return
+	}
+
+	s.remote.mu.Lock()
+	defer s.remote.mu.Unlock()
+	r := &s.remote
+	if r.controller != peerID {
+		return
+	}
+
+	out := input.Event{Type: ev.Type, Button: ev.Button, DX: ev.DX, DY: ev.DY, Key: ev.Key}
+	switch ev.Type {
+	case input.MouseMove, input.MouseDown, input.MouseUp:
+		bounds := src.ScreenBounds()
+		if bounds.Empty() {
+			return
+		}
+		r.x = bounds.Min.X + clampIndex(ev.X, bounds.Dx())
+		r.y = bounds.Min.Y + clampIndex(ev.Y, bounds.Dy())
+		out.X, out.Y = r.x, r.y
+	}
+	if err := s.cfg.Input.Inject(out); err != nil {
+		log.Println("inject input failed:", err)
+		return
+	}
+	switch ev.Type {
+	case input.MouseDown:
+		r.buttons[ev.Button] = true
+	case input.MouseUp:
+		delete(r.buttons, ev.Button)
+	case input.KeyDown:
+		r.keys[ev.Key] = true
+	case input.KeyUp:
+		delete(r.keys, ev.Key)
+	}
+}
+
+// clampIndex 把 [0, 1] 内的归一化坐标换算为 [0, n) 内的像素下标
+func clampIndex(v float64, n int) int {
+	i := int(v * float64(n))
+	return max(0, min(i, n-1))
+}
+
+// -------------------- Viewer 侧 --------------------
+
+// RequestRemoteControl 向观看的 Publisher 申请（enable 为 true）或放弃远程控制，
+// 结果通过 ViewerConfig.OnRemoteControl 回调
+func (v *Viewer) RequestRemoteControl(enable bool) error {
+	s := v.s
+	s.mu.Lock()
+	ctrl := s.ctrl
+	if !enable {
+		s.controlling = false
+	}
+	s.mu.Unlock()
+	if ctrl == nil || ctrl.ReadyState() != webrtc.DataChannelStateOpen {
+		return errors.New("control 通道尚未就绪")
+	}
+	return sendControl(ctrl, controlTypeRemote, remoteControlPayload{Enabled: enable})
+}
+
+// SendInput 把输入事件发给 Publisher，未获得控制权时静默丢弃
+func (v *Viewer) SendInput(ev InputEvent) error {
+	s := v.s
+	s.mu.Lock()
+	dc := s.input
+	controlling := s.controlling
+	s.mu.Unlock()
+	if !controlling || dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return nil
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return dc.SendText(string(b))
+}
+
+func (s *viewerSession) handleRemoteControl(data json.RawMessage) {
+	var p remoteControlPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("viewer parse remote_control error:", err)
+		return
+	}
+	s.mu.Lock()
+	s.controlling = p.Enabled
+	s.mu.Unlock()
+	if s.onRemote != nil {
+		s.onRemote(p.Enabled, p.Reason)
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"encoding/json"
+	"image"
+	"slices"
+	"testing"
+
+	"snap-screen/pkg/input"
+)
+
+// boundedSource 模拟屏幕来源，捕获区域为副屏上的 bounds
+type boundedSource struct {
+	bounds image.Rectangle
+}
+
+func (b boundedSource) CaptureFrame() *image.RGBA { return image.NewRGBA(b.bounds) }
+
+func (b boundedSource) ScreenBounds() image.Rectangle { return b.bounds }
+
+// newRemotePublisher 创建只用于远程控制的 Publisher，peers 为已连接的 Viewer
+func newRemotePublisher(t *testing.T, peers ...string) (*Publisher, *input.RecordingInjector) {
+	t.Helper()
+	rec := input.NewRecordingInjector()
+	s := &Publisher{
+		source: boundedSource{bounds: image.Rect(1920, 0, 3840, 1080)},
+		cfg:    PublisherConfig{Input: rec},
+		peers:  make(map[string]*peerSession),
+	}
+	for _, id := range peers {
+		s.peers[id] = &peerSession{}
+	}
+	return s, rec
+}
+
+// sendInput 以 peerID 的身份发送一个输入事件
+func sendInput(t *testing.T, s *Publisher, peerID string, ev InputEvent) {
+	t.Helper()
+	b, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.handleInput(peerID, b)
+}
+
+func TestHandleInputDropsNonController(t *testing.T) {
+	s, rec := newRemotePublisher(t, "a", "b")
+	sendInput(t, s, "a", InputEvent{Type: input.MouseMove, X: 0.5, Y: 0.5})
+	if got := rec.Events(); len(got) != 0 {
+		t.Fatalf("未授权时注入了事件: %v", got)
+	}
+
+	if err := s.GrantControl("a"); err != nil {
+		t.Fatal(err)
+	}
+	sendInput(t, s, "b", InputEvent{Type: input.KeyDown, Key: "A"})
+	sendInput(t, s, "a", InputEvent{Type: input.KeyDown, Key: "B"})
+	want := []input.Event{{Type: input.KeyDown, Key: "B"}}
+	if got := rec.Events(); !slices.Equal(got, want) {
+		t.Fatalf("注入的事件 = %v, 期望只有控制者的 %v", got, want)
+	}
+}
+
+func TestHandleInputClampsCoordinates(t *testing.T) {
+	s, rec := newRemotePublisher(t, "a")
+	if err := s.GrantControl("a"); err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []InputEvent{
+		{Type: input.MouseMove, X: 0.5, Y: 0.5},
+		{Type: input.MouseMove, X: -0.3, Y: 1.7},
+		{Type: input.MouseMove, X: 1, Y: 0},
+	} {
+		sendInput(t, s, "a", ev)
+	}
+	want := []input.Event{
+		{Type: input.MouseMove, X: 2880, Y: 540},
+		{Type: input.MouseMove, X: 1920, Y: 1079},
+		{Type: input.MouseMove, X: 3839, Y: 0},
+	}
+	if got := rec.Events(); !slices.Equal(got, want) {
+		t.Fatalf("注入的事件 = %v, 期望 %v", got, want)
+	}
+}
+
+func TestReleaseControlLiftsHeldInput(t *testing.T) {
+	s, rec := newRemotePublisher(t, "a")
+	if err := s.GrantControl("a"); err != nil {
+		t.Fatal(err)
+	}
+	sendInput(t, s, "a", InputEvent{Type: input.KeyDown, Key: "LeftShift"})
+	sendInput(t, s, "a", InputEvent{Type: input.KeyDown, Key: "A"})
+	sendInput(t, s, "a", InputEvent{Type: input.KeyUp, Key: "A"})
+	sendInput(t, s, "a", InputEvent{Type: input.MouseDown, Button: input.ButtonLeft, X: 0.25, Y: 0.5})
+	rec.Reset()
+
+	if got := s.releaseControl(); got != "a" {
+		t.Fatalf("releaseControl() = %q, 期望 a", got)
+	}
+	want := []input.Event{
+		{Type: input.KeyUp, Key: "LeftShift"},
+		{Type: input.MouseUp, Button: input.ButtonLeft, X: 2400, Y: 540},
+	}
+	if got := rec.Events(); !slices.Equal(got, want) {
+		t.Fatalf("收回控制时注入 %v, 期望 %v", got, want)
+	}
+	if c := s.Controller(); c != "" {
+		t.Fatalf("收回后控制者仍为 %q", c)
+	}
+
+	// 收回之后原控制者的事件被丢弃
+	rec.Reset()
+	sendInput(t, s, "a", InputEvent{Type: input.KeyDown, Key: "A"})
+	if got := rec.Events(); len(got) != 0 {
+		t.Fatalf("收回后仍注入了事件: %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"image"
+	"time"
+
+	"snap-screen/pkg/codec"
+)
+
+// Frame 为 Viewer 收到并解码后的一帧
+type Frame struct {
+	Image image.Image
+	// Seq 为 Publisher 的帧序号，不连续说明中间有帧被 Publisher 丢弃
+	Seq uint32
+	// Captured 为 Publisher 采集该帧的时间（Publisher 的时钟），占位帧为零值
+	Captured time.Time
+	// Received 为本端解码完成的时间
+	Received time.Time
+	// Region 为该帧显示的区域，见 zoom.go
+	Region ZoomRegion
+	// JPEG 为该帧的原始 JPEG 数据，只在帧以单条带 JPEG 传输时不为 nil，
+	// 写 MJPEG 等场景可直接使用而无需重新编码；调用方不得修改
+	JPEG []byte
+}
+
+// FrameSink 接收 Viewer 解码后的每一帧，用于无界面观看（保存图片、转发、测速等）。
+// WriteFrame 在帧通道的回调中串行调用，耗时过长会拖慢收帧；返回的错误只记录日志。
+type FrameSink interface {
+	WriteFrame(f Frame) error
+}
+
+// newFrame 由帧头、解码结果和各条带的编码数据组成 Frame
+func newFrame(h frameHeader, img image.Image, parts [][]byte) Frame {
+	f := Frame{
+		Image:    img,
+		Seq:      h.Seq,
+		Received: time.Now(),
+		Region:   h.Region,
+	}
+	if h.Captured != 0 {
+		f.Captured = time.Unix(0, h.Captured)
+	}
+	if h.Codec == codec.JPEG && len(parts) == 1 {
+		f.JPEG = parts[0]
+	}
+	return f
+}
@@ -0,0 +1,275 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"snap-screen/pkg/transfer"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// 原始分辨率截图：推流画面经过缩放和有损编码，需要逐像素清晰的画面时，Viewer 发送 request_snapshot，
+// Publisher 的流水线在下一次采集时另外保留一份原始分辨率的画面并叠加水印，编码为 PNG 后
+// 经 files 通道分块发回（Offer.Kind 为 snapshot）。
+// Viewer 只自动接收自己请求过的截图。Publisher 可以关闭该功能，或限制同一 Viewer 的请求频率。
+
+// snapshotKind 为截图传输的 transfer.Offer.Kind
+const snapshotKind = "snapshot"
+
+const (
+	// 同一 Viewer 两次截图请求的默认最小间隔
+	defaultSnapshotInterval = 5 * time.Second
+	// Viewer 等待截图的超时，超时后允许重新请求（对方可能是不支持截图的旧版本）
+	snapshotRequestTimeout = 30 * time.Second
+	// 等待流水线采集截图的超时；没有订阅者时流水线不采集
+	snapshotCaptureTimeout = 5 * time.Second
+)
+
+// errSnapshotPaused 表示截图时分享已暂停，不能发送真实画面
+var errSnapshotPaused = errors.New("对方已暂停分享")
+
+// snapshotResult 为流水线为截图请求保留的原始分辨率画面，已叠加水印和烧录标注
+type snapshotResult struct {
+	frame    *image.RGBA
+	captured time.Time
+	err      error
+}
+
+// snapshotPayload 为 snapshot 控制消息，Publisher 拒绝或截图失败时发给 Viewer
+type snapshotPayload struct {
+	Error string `json:"error"`
+}
+
+// handleSnapshotRequest 检查是否允许截图，允许时在后台采集并发送
+func (s *Publisher) handleSnapshotRequest(peerID string) {
+	paused := s.isPaused()
+	s.mu.Lock()
+	peer, ok := s.peers[peerID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	ctrl, files := peer.ctrl, peer.files
+	reason := ""
+	switch {
+	case s.cfg.DisableSnapshots:
+		reason = "对方未开启原始分辨率截图"
+	case files == nil:
+		reason = "该 Viewer 不支持文件传输"
+	case paused:
+		reason = "对方已暂停分享"
+	}
+	if interval := s.snapshotInterval(); reason == "" && interval > 0 {
+		if wait := interval - time.Since(peer.lastSnapshot); wait > 0 {
+			reason = fmt.Sprintf("请求过于频繁，请 %d 秒后再试", int(wait.Seconds())+1)
+		}
+	}
+	if reason == "" {
+		peer.lastSnapshot = time.Now()
+	}
+	s.mu.Unlock()
+
+	if reason == "" && !s.snapshotMu.TryLock() {
+		// 原始分辨率的采集和 PNG 编码开销较大，同一时刻只处理一个请求
+		reason = "上一张截图仍在处理中，请稍后再试"
+	}
+	if reason != "" {
+		log.Println("reject snapshot from", peerID+":", reason)
+		_ = sendControl(ctrl, controlTypeSnapshot, snapshotPayload{Error: reason})
+		return
+	}
+
+	// 不阻塞 control 通道上的其他消息
+	go func() {
+		defer s.snapshotMu.Unlock()
+		if err := s.sendSnapshot(files); err != nil {
+			log.Println("send snapshot to", peerID, "failed:", err)
+			_ = sendControl(ctrl, controlTypeSnapshot, snapshotPayload{Error: "截图失败: " + err.Error()})
+			return
+		}
+		log.Println("snapshot sent to", peerID)
+	}()
+}
+
+func (s *Publisher) snapshotInterval() time.Duration {
+	if s.cfg.SnapshotInterval == 0 {
+		return defaultSnapshotInterval
+	}
+	return s.cfg.SnapshotInterval
+}
+
+// sendSnapshot 取得一帧原始分辨率、已叠加水印和标注的画面，编码为 PNG 发送
+func (s *Publisher) sendSnapshot(files *transfer.Manager) error {
+	frame, captured, err := s.captureSnapshot()
+	if err != nil {
+		return err
+	}
+	// 等待期间可能已经暂停，此时不能再把真实画面发出去
+	if s.isPaused() {
+		return errSnapshotPaused
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := enc.Encode(&buf, frame); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("snapshot-%s.png", captured.Format("20060102-150405"))
+	_, err = files.SendBytes(name, snapshotKind, buf.Bytes())
+	return err
+}
+
+// captureSnapshot 把截图请求交给流水线并等待结果。采集和绘制水印都在流水线的 goroutine 上进行：
+// 帧来源不能被并发采集（MJPEG 回放会因此跳帧），overlay 和标注的渲染器也不是并发安全的
+func (s *Publisher) captureSnapshot() (*image.RGBA, time.Time, error) {
+	result := make(chan snapshotResult, 1)
+	select {
+	case s.snapshotReq <- result:
+	case <-s.ctx.Done():
+		return nil, time.Time{}, errors.New("推流已停止")
+	}
+	timer := time.NewTimer(snapshotCaptureTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-result:
+		return r.frame, r.captured, r.err
+	case <-timer.C:
+		// 撤回还没被采集阶段取走的请求，已取走的结果写入 result 后随之丢弃
+		select {
+		case <-s.snapshotReq:
+		default:
+		}
+		return nil, time.Time{}, errors.New("采集画面超时")
+	case <-s.ctx.Done():
+		return nil, time.Time{}, errors.New("推流已停止")
+	}
+}
+
+// rejectSnapshot 以 err 结束等待中的截图请求，没有请求时什么也不做；只在采集阶段调用
+func (s *Publisher) rejectSnapshot(err error) {
+	select {
+	case result := <-s.snapshotReq:
+		result <- snapshotResult{err: err}
+	default:
+	}
+}
+
+// cloneFrame 复制一帧，保留原有的坐标范围
+func cloneFrame(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
+
+// -------------------- Viewer 侧 --------------------
+
+// RequestSnapshot 向观看的 Publisher 请求一张原始分辨率的 PNG 截图，
+// 结果通过 ViewerConfig.OnSnapshot 回调
+func (v *Viewer) RequestSnapshot() error {
+	s := v.s
+	if s.onSnapshot == nil {
+		return errors.New("未配置 OnSnapshot，无法接收截图")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.snapshotRequested.IsZero() && time.Since(s.snapshotRequested) < snapshotRequestTimeout {
+		return errors.New("上一次截图请求尚未完成")
+	}
+	if s.files == nil {
+		return errors.New("文件通道尚未建立")
+	}
+	if s.ctrl == nil || s.ctrl.ReadyState() != webrtc.DataChannelStateOpen {
+		return errors.New("control 通道尚未就绪")
+	}
+	if err := sendControl(s.ctrl, controlTypeRequestSnapshot, nil); err != nil {
+		return err
+	}
+	s.snapshotRequested = time.Now()
+	return nil
+}
+
+// handleFileOffer 自动接收请求过的截图，其他文件交给 OnFileOffer
+func (s *viewerSession) handleFileOffer(m *transfer.Manager, o transfer.Offer) {
+	if o.Kind != snapshotKind {
+		if s.onFileOffer == nil {
+			_ = m.Reject(o.ID, "对方未开启文件接收")
+			return
+		}
+		s.onFileOffer(o)
+		return
+	}
+
+	s.mu.Lock()
+	requested := !s.snapshotRequested.IsZero()
+	if requested {
+		s.snapshotRequested = time.Time{}
+		s.snapshotIDs[o.ID] = true
+	}
+	s.mu.Unlock()
+	if !requested {
+		_ = m.Reject(o.ID, "未请求截图")
+		return
+	}
+	if err := m.Accept(o.ID, s.snapshotDir); err != nil {
+		s.mu.Lock()
+		delete(s.snapshotIDs, o.ID)
+		s.mu.Unlock()
+		s.onSnapshot("", err)
+	}
+}
+
+// handleTransfer 转发传输进度，截图传输结束时另外回调 OnSnapshot
+func (s *viewerSession) handleTransfer(p transfer.Progress) {
+	if s.onTransfer != nil {
+		s.onTransfer(p)
+	}
+	if !p.State.Finished() {
+		return
+	}
+	s.mu.Lock()
+	ok := s.snapshotIDs[p.ID]
+	delete(s.snapshotIDs, p.ID)
+	s.mu.Unlock()
+	if !ok || s.onSnapshot == nil {
+		return
+	}
+	err := p.Err
+	if err == nil && p.State != transfer.StateCompleted {
+		err = errors.New("截图传输未完成")
+	}
+	if err != nil {
+		s.onSnapshot("", err)
+		return
+	}
+	s.onSnapshot(p.Path, nil)
+}
+
+// handleSnapshot 处理 Publisher 拒绝截图或截图失败的通知
+func (s *viewerSession) handleSnapshot(data json.RawMessage) {
+	var p snapshotPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("viewer parse snapshot error:", err)
+		return
+	}
+	s.mu.Lock()
+	s.snapshotRequested = time.Time{}
+	s.mu.Unlock()
+	if s.onSnapshot != nil {
+		s.onSnapshot("", errors.New(p.Error))
+	}
+}
+
+// defaultSnapshotDir 为 ViewerConfig.SnapshotDir 的默认值
+func defaultSnapshotDir() string {
+	return filepath.Join(os.TempDir(), "snap-screen", "snapshots")
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"snap-screen/pkg/codec"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// PublisherStats 是 Publisher 会话某一时刻的统计快照
+type PublisherStats struct {
+	StreamID string
+	Viewers  []ViewerStats
+	Pipeline PipelineStats
+	// Annotations 为当前有效的标注数量
+	Annotations int
+}
+
+// ViewerStats 描述单个 Viewer 连接的传输状况
+type ViewerStats struct {
+	PeerID          string
+	ConnectionState string
+	Codec           string // 协商出的帧编码
+	// Zoom 为该 Viewer 正在放大查看的区域，零值为完整画面
+	Zoom ZoomRegion
+	// Recording 为该 Viewer 是否正在录制
+	Recording bool
+	// LocalCandidate / RemoteCandidate 为当前选中的 ICE 候选对，未选中时为空
+	LocalCandidate  string
+	RemoteCandidate string
+	RTT             time.Duration
+	// Latency 为采集到 Viewer 显示的端到端延迟（滑动平均），ClockOffset 为估算的
+	// Viewer 时钟减 Publisher 时钟；尚未测得时均为 0
+	Latency     time.Duration
+	ClockOffset time.Duration
+	BytesSent   uint64
+	FramesSent  uint64
+	SendErrors  uint64
+	// Dropped 为发送缓冲积压时跳过的帧数，持续增长说明该 Viewer 的网络跟不上
+	Dropped        uint64
+	BufferedAmount uint64
+}
+
+// PipelineStats 描述采集 → 缩放 → 编码流水线的耗时，时间为指数滑动平均
+type PipelineStats struct {
+	Frames uint64
+	// Dropped 为下游阶段来不及处理、被更新的帧替换掉的帧数
+	Dropped    uint64
+	Capture    time.Duration
+	Scale      time.Duration
+	Overlay    time.Duration
+	Encode     time.Duration
+	FrameBytes int // 最近一帧编码后的大小
+}
+
+// pipelineMeter 记录流水线各阶段耗时
+type pipelineMeter struct {
+	mu    sync.Mutex
+	stats PipelineStats
+}
+
+// 滑动平均权重，越大越贴近最近几帧
+const pipelineEWMAWeight = 0.1
+
+func (m *pipelineMeter) record(capture, scale, overlay, encode time.Duration, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := &m.stats
+	if st.Frames == 0 {
+		st.Capture, st.Scale, st.Overlay, st.Encode = capture, scale, overlay, encode
+	} else {
+		st.Capture = ewma(st.Capture, capture)
+		st.Scale = ewma(st.Scale, scale)
+		st.Overlay = ewma(st.Overlay, overlay)
+		st.Encode = ewma(st.Encode, encode)
+	}
+	st.Frames++
+	st.FrameBytes = size
+}
+
+func (m *pipelineMeter) drop() {
+	m.mu.Lock()
+	m.stats.Dropped++
+	m.mu.Unlock()
+}
+
+func (m *pipelineMeter) snapshot() PipelineStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+func ewma(avg, sample time.Duration) time.Duration {
+	return avg + time.Duration(pipelineEWMAWeight*float64(sample-avg))
+}
+
+// Stats 返回当前会话的统计快照，包括每个 Viewer 的连接数据和流水线耗时
+func (s *Publisher) Stats() PublisherStats {
+	type peerRef struct {
+		id    string
+		peer  *peerSession
+		dc    *webrtc.DataChannel
+		codec codec.ID
+		zoom  ZoomRegion
+		rec   bool
+	}
+	s.mu.RLock()
+	refs := make([]peerRef, 0, len(s.peers))
+	for id, peer := range s.peers {
+		refs = append(refs, peerRef{id: id, peer: peer, dc: peer.dc, codec: peer.codec, zoom: peer.zoom, rec: peer.recording})
+	}
+	s.mu.RUnlock()
+	sort.Slice(refs, func(i, j int) bool { return refs[i].id < refs[j].id })
+
+	out := PublisherStats{
+		StreamID:    s.streamID,
+		Viewers:     make([]ViewerStats, 0, len(refs)),
+		Pipeline:    s.meter.snapshot(),
+		Annotations: s.board.Len(time.Now()),
+	}
+	for _, ref := range refs {
+		// GetStats 可能较慢，放在锁外执行
+		st := ref.peer.stats(ref.id, ref.dc)
+		st.Codec = ref.codec.String()
+		st.Zoom = ref.zoom
+		st.Recording = ref.rec
+		out.Viewers = append(out.Viewers, st)
+	}
+	return out
+}
+
+func (p *peerSession) stats(peerID string, dc *webrtc.DataChannel) ViewerStats {
+	st := ViewerStats{
+		PeerID:     peerID,
+		SendErrors: atomic.LoadUint64(&p.sendErrors),
+		Dropped:    atomic.LoadUint64(&p.dropped),
+	}
+	if p.pc == nil {
+		return st
+	}
+	st.ConnectionState = p.pc.ConnectionState().String()
+	st.Latency, st.ClockOffset = p.latency.snapshot()
+
+	ice := p.pc.SCTP().Transport().ICETransport()
+	if pair, err := ice.GetSelectedCandidatePair(); err == nil && pair != nil {
+		st.LocalCandidate = pair.Local.String()
+		st.RemoteCandidate = pair.Remote.String()
+	}
+	if pairStats, ok := ice.GetSelectedCandidatePairStats(); ok {
+		st.RTT = time.Duration(pairStats.CurrentRoundTripTime * float64(time.Second))
+	}
+
+	if dc == nil {
+		return st
+	}
+	st.BufferedAmount = dc.BufferedAmount()
+	for _, v := range p.pc.GetStats() {
+		if dcStats, ok := v.(webrtc.DataChannelStats); ok && dcStats.Label == dc.Label() {
+			st.BytesSent = dcStats.BytesSent
+			st.FramesSent = uint64(dcStats.MessagesSent)
+		}
+	}
+	return st
+}
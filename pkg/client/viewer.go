@@ -1,38 +1,114 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
-	"image/jpeg"
+	"image"
 	"log"
+	"snap-screen/pkg/annotate"
+	"snap-screen/pkg/clipboard"
+	"snap-screen/pkg/codec"
+	"snap-screen/pkg/transfer"
 	"snap-screen/pkg/utils"
 	"sync"
+	"time"
 
-	"fyne.io/fyne/v2/canvas"
 	"github.com/gorilla/websocket"
 	"github.com/pion/webrtc/v4"
 )
 
 type viewerSession struct {
-	streamID  string
+	streamID string
+	// peerID 每次重连都会更换，Publisher 把重连后的会话当作新的 Viewer
 	peerID    string
 	signalURL string
+	ice       ICEConfig
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	ws *websocket.Conn
-	pc *webrtc.PeerConnection
-	dc *webrtc.DataChannel
-
-	img *canvas.Image
+	ws   *websocket.Conn
+	pc   *webrtc.PeerConnection
+	dc   *webrtc.DataChannel
+	ctrl *webrtc.DataChannel
+	// input 通道只在获得远程控制权后使用
+	input       *webrtc.DataChannel
+	controlling bool
+
+	onState   func(StreamState)
+	onLatency func(time.Duration)
+	onRemote  func(bool, string)
+	codecs    []codec.ID
+
+	// 标注镜像，ViewerConfig.Annotations 为 nil 时不处理标注
+	annotations       *annotate.Board
+	annotationsBurned bool
+	onCleared         func(by string)
+
+	// 剪贴板共享，clipboard 为 nil 时忽略对方推送；clipboardPolicy 在 Publisher 告知前为 nil
+	clipboard         clipboard.Clipboard
+	clipboardLimits   clipboard.Limits
+	clipboardPolicy   *clipboardPolicy
+	onClipboard       func(clipboard.Content, error)
+	onClipboardPolicy func(bool)
+
+	// 文件传输，见 files.go
+	filesDC     *webrtc.DataChannel
+	files       *transfer.Manager
+	onFileOffer func(transfer.Offer)
+	onTransfer  func(transfer.Progress)
+
+	// 原始分辨率截图，见 snapshot.go。snapshotRequested 为已发出、尚未收到 offer 的请求时刻
+	snapshotDir       string
+	snapshotRequested time.Time
+	snapshotIDs       map[string]bool
+	onSnapshot        func(path string, err error)
+
+	// zoom 为最近显示的一帧所对应的区域，见 zoom.go
+	zoom   ZoomRegion
+	onZoom func(ZoomRegion)
+
+	// 最近显示的一帧，供 ackLoop 确认
+	lastFrame displayedFrame
+
+	// 按编码缓存的解码器，只在帧通道的 OnMessage 回调中使用（回调串行执行）
+	decoders map[codec.ID]codec.Decoder
+
+	// sink 不为 nil 时每一帧先交给它，见 sink.go；recorder 为正在录制的 sink，见 recording.go
+	sink     FrameSink
+	recorder FrameSink
+	sinkMu   sync.Mutex
+
+	// Viewer.Frames / Viewer.Events 的通道，见 events.go；chanMu 保护发送与关闭
+	frames      chan Frame
+	events      chan ViewerEvent
+	chanMu      sync.Mutex
+	chansClosed bool
+	// droppedEvents 为 Events 通道已满时丢弃的事件数，受 chanMu 保护
+	droppedEvents uint64
 
 	mu sync.Mutex
+	// gorilla/websocket 不允许并发写，ICE 回调与主流程共用这把锁
+	writeMu sync.Mutex
 
 	remoteSet   bool
 	pendingICEs []webrtc.ICECandidateInit
+
+	// 连接状态与重连，见 reconnect.go。gen 为当前连接的序号，旧连接的回调据此忽略
+	gen            int
+	state          ViewerState
+	stateDetail    string
+	stateSince     time.Time
+	connectStarted time.Time
+	pcUp           bool
+	frameAt        time.Time
+	reconnect      ReconnectConfig
+	backoff        time.Duration
+	failingSince   time.Time
+	lost           chan lostConn
+	removedGen     int
+	onViewerState  func(ViewerState, string)
 }
 
 var (
@@ -40,18 +116,60 @@ var (
 	activeViewer *viewerSession
 )
 
-// StartViewer 初始化 WebRTC 观看
-func StartViewer(streamID string, img *canvas.Image, signalURL string) error {
-	if streamID == "" {
-		return errors.New("stream id 不能为空")
-	}
-	if signalURL == "" {
-		signalURL = defaultSignalURL
-	}
-	if img == nil {
-		return errors.New("image 组件不能为空")
+// Viewer 为一个观看会话。画面通过 Frames 通道或 ViewerConfig.Sink 交给调用方，
+// 连接状态等通过 Events 通道或 ViewerConfig 中的回调通知，不依赖任何界面库。
+type Viewer struct {
+	s *viewerSession
+}
+
+// NewViewer 订阅 streamID 并开始观看，用完后调用 Stop。
+// 每个 Viewer 是独立的会话，可以同时观看多个 stream（或同一 stream 多次）。
+func NewViewer(streamID string, cfg ViewerConfig) (*Viewer, error) {
+	s, err := startViewerSession(streamID, cfg)
+	if err != nil {
+		return nil, err
 	}
+	return &Viewer{s: s}, nil
+}
+
+// StreamID 返回观看的 stream ID
+func (v *Viewer) StreamID() string {
+	return v.s.streamID
+}
+
+// Frames 返回解码后的帧。通道只保留最新一帧，读取不及时时旧帧被丢弃；会话结束后通道关闭
+func (v *Viewer) Frames() <-chan Frame {
+	return v.s.frames
+}
+
+// Events 返回连接状态、错误、stream 移除等事件，会话结束后通道关闭。
+// 读取是可选的，连接状态和暂停状态也可以通过 ViewerConfig 的 OnViewerState / OnStreamState 获得。
+// 通道有缓冲，读取不及时或从不读取时新事件被直接丢弃，丢弃的数量见 DroppedEvents
+func (v *Viewer) Events() <-chan ViewerEvent {
+	return v.s.events
+}
 
+// DroppedEvents 返回 Events 通道已满而丢弃的事件数
+func (v *Viewer) DroppedEvents() uint64 {
+	v.s.chanMu.Lock()
+	defer v.s.chanMu.Unlock()
+	return v.s.droppedEvents
+}
+
+// Stop 结束观看，可重复调用
+func (v *Viewer) Stop() {
+	v.s.stop()
+}
+
+// StartViewer 初始化 WebRTC 观看，画面交给 sink
+func StartViewer(streamID string, sink FrameSink, signalURL string) error {
+	_, err := StartViewerWithConfig(streamID, ViewerConfig{SignalURL: signalURL, Sink: sink})
+	return err
+}
+
+// StartViewerWithConfig 与 StartViewer 相同，但允许指定 ICE 等额外参数。
+// StartViewer 系列只维护一个当前会话，启动新会话时之前的会被停止；需要同时观看多路时使用 NewViewer。
+func StartViewerWithConfig(streamID string, cfg ViewerConfig) (*Viewer, error) {
 	viewerMu.Lock()
 	defer viewerMu.Unlock()
 	if activeViewer != nil {
@@ -59,36 +177,15 @@ func StartViewer(streamID string, img *canvas.Image, signalURL string) error {
 		activeViewer.stop()
 		activeViewer = nil
 	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	s := &viewerSession{
-		streamID:  streamID,
-		peerID:    utils.GenID(),
-		signalURL: signalURL,
-		ctx:       ctx,
-		cancel:    cancel,
-		img:       img,
-	}
-
-	if err := s.connectAndSubscribe(); err != nil {
-		cancel()
-		return err
-	}
-	if err := s.createPeerConnection(); err != nil {
-		s.stop()
-		return err
-	}
-	if err := s.createAndSendOffer(); err != nil {
-		s.stop()
-		return err
+	s, err := startViewerSession(streamID, cfg)
+	if err != nil {
+		return nil, err
 	}
-
 	activeViewer = s
-	go s.readLoop()
-	return nil
+	return &Viewer{s: s}, nil
 }
 
-// StopViewer 停止观看
+// StopViewer 停止当前会话
 func StopViewer() {
 	viewerMu.Lock()
 	s := activeViewer
@@ -102,51 +199,156 @@ func StopViewer() {
 
 // -------------------- Viewer 内部实现 --------------------
 
-func (s *viewerSession) connectAndSubscribe() error {
-	ws, _, err := websocket.DefaultDialer.Dial(s.signalURL, nil)
+// startViewerSession 填充默认值，连接信令服务器并发出 Offer
+func startViewerSession(streamID string, cfg ViewerConfig) (*viewerSession, error) {
+	if streamID == "" {
+		return nil, errors.New("stream id 不能为空")
+	}
+	if cfg.SignalURL == "" {
+		cfg.SignalURL = defaultSignalURL
+	}
+	if len(cfg.Codecs) == 0 {
+		cfg.Codecs = codec.Supported()
+	}
+	if cfg.SnapshotDir == "" {
+		cfg.SnapshotDir = defaultSnapshotDir()
+	}
+	cfg.Reconnect.normalize()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &viewerSession{
+		streamID:          streamID,
+		signalURL:         cfg.SignalURL,
+		ice:               cfg.ICE,
+		onState:           cfg.OnStreamState,
+		onLatency:         cfg.OnLatency,
+		onRemote:          cfg.OnRemoteControl,
+		codecs:            cfg.Codecs,
+		annotations:       cfg.Annotations,
+		onCleared:         cfg.OnAnnotationsCleared,
+		clipboard:         cfg.Clipboard,
+		clipboardLimits:   cfg.ClipboardLimits,
+		onClipboard:       cfg.OnClipboard,
+		onClipboardPolicy: cfg.OnClipboardPolicy,
+		onFileOffer:       cfg.OnFileOffer,
+		onTransfer:        cfg.OnTransfer,
+		snapshotDir:       cfg.SnapshotDir,
+		snapshotIDs:       make(map[string]bool),
+		onSnapshot:        cfg.OnSnapshot,
+		onZoom:            cfg.OnZoom,
+		decoders:          make(map[codec.ID]codec.Decoder),
+		ctx:               ctx,
+		cancel:            cancel,
+		sink:              cfg.Sink,
+		frames:            make(chan Frame, viewerFrameBuffer),
+		events:            make(chan ViewerEvent, viewerEventBuffer),
+		reconnect:         cfg.Reconnect,
+		lost:              make(chan lostConn, 1),
+		onViewerState:     cfg.OnViewerState,
+	}
+
+	if err := s.connect(); err != nil {
+		s.end(err)
+		return nil, err
+	}
+
+	go s.superviseLoop()
+	go s.watchLoop()
+	go s.ackLoop()
+	return s, nil
+}
+
+// connect 建立一次完整连接：连接信令服务器并订阅、创建 PeerConnection、发出 Offer。
+// 失败时由调用方 teardown。
+func (s *viewerSession) connect() error {
+	s.mu.Lock()
+	s.gen++
+	gen := s.gen
+	s.peerID = utils.GenID()
+	s.remoteSet = false
+	s.pendingICEs = nil
+	s.clipboardPolicy = nil
+	s.pcUp = false
+	s.connectStarted = time.Now()
+	first := gen == 1
+	s.mu.Unlock()
+	if first {
+		s.setState(ViewerStateConnecting, "正在连接 "+s.signalURL, nil)
+	}
+
+	ws, err := s.subscribe()
 	if err != nil {
 		return err
 	}
+	go s.readLoop(ws, gen)
+	if err := s.createPeerConnection(gen); err != nil {
+		return err
+	}
+	return s.createAndSendOffer()
+}
+
+// subscribe 连接信令服务器并订阅 stream
+func (s *viewerSession) subscribe() (*websocket.Conn, error) {
+	ws, _, err := websocket.DefaultDialer.Dial(s.signalURL, nil)
+	if err != nil {
+		return nil, err
+	}
 	s.mu.Lock()
+	old := s.ws
 	s.ws = ws
 	s.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
 
-	msg := signalMessage{
-		Type:     "subscribe",
-		StreamID: s.streamID,
-		PeerID:   s.peerID,
+	if err := s.writeSignal(signalMessage{Type: "subscribe"}); err != nil {
+		return nil, err
 	}
-	return s.writeSignal(msg)
+	return ws, nil
 }
 
-func (s *viewerSession) createPeerConnection() error {
-	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{URLs: []string{"stun:stun.l.google.com:19302"}},
-		},
-	})
+func (s *viewerSession) createPeerConnection(gen int) error {
+	pc, err := newPeerConnection(s.ice)
 	if err != nil {
 		return err
 	}
 
+	pc.OnConnectionStateChange(func(st webrtc.PeerConnectionState) {
+		if !s.current(gen) {
+			return
+		}
+		switch st {
+		case webrtc.PeerConnectionStateConnected:
+			s.mu.Lock()
+			s.pcUp = true
+			s.mu.Unlock()
+			s.emit(ViewerEvent{Type: ViewerEventConnected})
+			s.setState(ViewerStateConnected, "WebRTC 连接已建立", nil)
+		case webrtc.PeerConnectionStateDisconnected:
+			err := errors.New("WebRTC 连接已中断")
+			s.emit(ViewerEvent{Type: ViewerEventDisconnected, Err: err})
+			// ICE 可能自行恢复，先进入 stalled，长时间未恢复再由 watchLoop 重新协商
+			s.setState(ViewerStateStalled, err.Error(), err)
+		case webrtc.PeerConnectionStateFailed:
+			err := errors.New("WebRTC 连接失败")
+			s.emit(ViewerEvent{Type: ViewerEventDisconnected, Err: err})
+			s.fail(gen, err)
+		}
+	})
+
 	pc.OnICECandidate(func(cand *webrtc.ICECandidate) {
-		if cand == nil {
+		if cand == nil || !s.current(gen) {
 			return
 		}
 		b, err := json.Marshal(cand.ToJSON())
 		if err != nil {
 			return
 		}
-		_ = s.writeSignal(signalMessage{
-			Type:     "ice_candidate",
-			StreamID: s.streamID,
-			PeerID:   s.peerID,
-			Data:     b,
-		})
+		_ = s.writeSignal(signalMessage{Type: "ice_candidate", Data: b})
 	})
 
 	// 作为 Offer 端，创建 DataChannel，这样 SCTP m= 行会出现在 Offer SDP 中
-	dc, err := pc.CreateDataChannel("screen-frames", nil)
+	dc, err := pc.CreateDataChannel(frameChannelLabel, nil)
 	if err != nil {
 		log.Println("viewer CreateDataChannel error:", err)
 	} else {
@@ -155,26 +357,107 @@ func (s *viewerSession) createPeerConnection() error {
 		s.mu.Unlock()
 
 		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
-			img, err := jpeg.Decode(bytes.NewReader(msg.Data))
+			h, parts, img, err := s.decodeFrame(msg.Data)
 			if err != nil {
 				log.Println("decode frame error:", err)
 				return
 			}
-			s.mu.Lock()
-			defer s.mu.Unlock()
-			if s.img != nil {
-				s.img.Image = img
-				s.img.Refresh()
+			s.frameReceived()
+			s.deliverFrame(newFrame(h, img, parts))
+			s.updateZoom(h.Region)
+			s.markDisplayed(h)
+		})
+	}
+
+	// control 通道承载状态通知等 JSON 消息，与帧数据分开避免互相阻塞
+	ctrl, err := pc.CreateDataChannel(controlChannelLabel, nil)
+	if err != nil {
+		log.Println("viewer CreateDataChannel control error:", err)
+	} else {
+		ctrl.OnOpen(func() {
+			// 告知 Publisher 本端支持的编码，Publisher 据此选择发送格式
+			if err := sendHello(ctrl, s.codecs); err != nil {
+				log.Println("viewer send hello error:", err)
 			}
+			s.notifyRecording(ctrl)
 		})
+		ctrl.OnMessage(func(msg webrtc.DataChannelMessage) {
+			s.handleControl(msg.Data)
+		})
+		s.mu.Lock()
+		s.ctrl = ctrl
+		s.mu.Unlock()
+	}
+
+	// 键鼠事件单独走一个有序可靠通道，不会被大块帧数据挤占
+	input, err := pc.CreateDataChannel(inputChannelLabel, nil)
+	if err != nil {
+		log.Println("viewer CreateDataChannel input error:", err)
+	}
+
+	// 文件传输使用独立通道，大文件不会阻塞画面和控制消息
+	if err := s.createFileChannel(pc); err != nil {
+		log.Println("viewer CreateDataChannel files error:", err)
 	}
 
 	s.mu.Lock()
 	s.pc = pc
+	s.input = input
 	s.mu.Unlock()
 	return nil
 }
 
+// decodeFrame 解析帧头并按其中的编码解码图像，多条带的帧并行解码后拼接；
+// 同时返回各条带的编码数据
+func (s *viewerSession) decodeFrame(data []byte) (frameHeader, [][]byte, image.Image, error) {
+	h, parts, err := unmarshalFrame(data)
+	if err != nil {
+		return h, nil, nil, err
+	}
+	dec, ok := s.decoders[h.Codec]
+	if !ok {
+		dec, err = codec.NewDecoder(h.Codec)
+		if err != nil {
+			return h, nil, nil, err
+		}
+		s.decoders[h.Codec] = dec
+	}
+	img, err := decodeStrips(dec, parts)
+	return h, parts, img, err
+}
+
+func (s *viewerSession) handleControl(data []byte) {
+	var msg controlMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Println("viewer parse control error:", err)
+		return
+	}
+	switch msg.Type {
+	case controlTypeState:
+		var st statePayload
+		if err := json.Unmarshal(msg.Data, &st); err != nil {
+			log.Println("viewer parse state error:", err)
+			return
+		}
+		if s.onState != nil {
+			s.onState(st.State)
+		}
+		s.emit(ViewerEvent{Type: ViewerEventStreamState, State: st.State})
+	case controlTypePing:
+		s.handlePing(msg.Data)
+	case controlTypeLatency:
+		s.handleLatency(msg.Data)
+	case controlTypeRemote:
+		s.handleRemoteControl(msg.Data)
+	case controlTypeAnnotate:
+		s.handleAnnotate(msg.Data)
+	case controlTypeClipboard:
+		s.handleClipboard(msg.Data)
+	case controlTypeSnapshot:
+		s.handleSnapshot(msg.Data)
+	}
+}
+
 func (s *viewerSession) createAndSendOffer() error {
 	s.mu.Lock()
 	pc := s.pc
@@ -190,9 +473,8 @@ func (s *viewerSession) createAndSendOffer() error {
 	if err := pc.SetLocalDescription(offer); err != nil {
 		return err
 	}
-	// 等待 ICE 收集完成，确保 SDP 中包含 ice-ufrag 等字段
-	gatherComplete := webrtc.GatheringCompletePromise(pc)
-	<-gatherComplete
+	// trickle ICE：不等待候选收集完成，立即发送 Offer；
+	// 先于 Offer 到达 Publisher 的候选会在对端缓存
 
 	local := pc.LocalDescription()
 	if local == nil {
@@ -203,29 +485,31 @@ func (s *viewerSession) createAndSendOffer() error {
 	if err != nil {
 		return err
 	}
-	return s.writeSignal(signalMessage{
-		Type:     "offer",
-		StreamID: s.streamID,
-		PeerID:   s.peerID,
-		Data:     b,
-	})
+	return s.writeSignal(signalMessage{Type: "offer", Data: b})
 }
 
-func (s *viewerSession) readLoop() {
+// readLoop 读取一条信令连接上的消息，直到连接断开或 gen 不再是当前连接
+func (s *viewerSession) readLoop(ws *websocket.Conn, gen int) {
 	for {
-		select {
-		case <-s.ctx.Done():
-			return
-		default:
-		}
-
-		_, data, err := s.ws.ReadMessage()
+		_, data, err := ws.ReadMessage()
 		if err != nil {
-			if s.ctx.Err() != nil {
+			if !s.current(gen) {
 				return
 			}
 			log.Println("viewer read signal error:", err)
-			s.stop()
+			s.emit(ViewerEvent{Type: ViewerEventError, Err: err})
+			s.mu.Lock()
+			pcUp := s.pcUp
+			s.mu.Unlock()
+			if pcUp {
+				// 画面仍在传输，只在后台重连信令服务器
+				go s.resignal(gen)
+			} else {
+				s.fail(gen, err)
+			}
+			return
+		}
+		if !s.current(gen) {
 			return
 		}
 
@@ -245,6 +529,7 @@ func (s *viewerSession) readLoop() {
 			}
 		case "error":
 			log.Println("viewer received error:", msg.Error)
+			s.signalError(gen, msg.Error)
 		}
 	}
 }
@@ -305,24 +590,56 @@ func (s *viewerSession) handleICE(msg signalMessage) error {
 	return nil
 }
 
+// writeSignal 发送信令消息，StreamID / PeerID 总是填当前会话的值
 func (s *viewerSession) writeSignal(msg signalMessage) error {
 	s.mu.Lock()
 	ws := s.ws
+	msg.StreamID, msg.PeerID = s.streamID, s.peerID
 	s.mu.Unlock()
 	if ws == nil {
 		return errors.New("信令连接不存在")
 	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	return ws.WriteJSON(msg)
 }
 
+// stop 结束会话，可重复调用
 func (s *viewerSession) stop() {
 	s.cancel()
+	s.teardown()
+	s.setState(ViewerStateEnded, "已停止观看", nil)
+	s.closeChans()
+}
 
+// teardown 关闭当前连接的所有资源，之后旧连接的回调都会被忽略
+func (s *viewerSession) teardown() {
 	s.mu.Lock()
+	s.gen++
+	s.pcUp = false
+	s.connectStarted = time.Time{}
+	controlling := s.controlling
+	snapshotPending := !s.snapshotRequested.IsZero()
+	s.snapshotRequested = time.Time{}
 	if s.dc != nil {
 		s.dc.Close()
 		s.dc = nil
 	}
+	if s.ctrl != nil {
+		s.ctrl.Close()
+		s.ctrl = nil
+	}
+	if s.input != nil {
+		s.input.Close()
+		s.input = nil
+	}
+	if s.filesDC != nil {
+		s.filesDC.Close()
+		s.filesDC = nil
+	}
+	files := s.files
+	s.files = nil
+	s.controlling = false
 	if s.pc != nil {
 		s.pc.Close()
 		s.pc = nil
@@ -332,6 +649,15 @@ func (s *viewerSession) stop() {
 		s.ws = nil
 	}
 	s.mu.Unlock()
-}
-
 
+	// 在锁外结束进行中的传输和通知回调，回调中可能再调用本包函数
+	if files != nil {
+		files.Close()
+	}
+	if controlling && s.onRemote != nil {
+		s.onRemote(false, "连接已断开，远程控制已结束")
+	}
+	if snapshotPending && s.onSnapshot != nil {
+		s.onSnapshot("", errors.New("连接已断开"))
+	}
+}
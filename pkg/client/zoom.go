@@ -0,0 +1,131 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"log"
+	"math"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// 放大查看：Viewer 通过 zoom 控制消息告知想看的区域，Publisher 从原始分辨率的采集帧中裁出该区域，
+// 按输出分辨率等比缩小（不放大）后单独编码，只发给该 Viewer。请求同一区域的 Viewer 共用一次编码。
+// 每一帧的帧头都带有它所显示的区域，Viewer 据此把鼠标、标注坐标换算回完整画面。
+
+// 最多放大 16 倍，再小的区域裁出来的像素太少，没有意义
+const minZoomSize = 1.0 / 16
+
+// zoomQuantum 为区域坐标的精度，与帧头中的 16 位定点数一致
+const zoomQuantum = 65535
+
+// ZoomRegion 为相对完整画面的归一化区域（0~1），零值表示完整画面
+type ZoomRegion struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// Full 返回是否为完整画面
+func (r ZoomRegion) Full() bool {
+	return r.W <= 0 || r.H <= 0 || (r.W >= 1 && r.H >= 1)
+}
+
+// Normalize 把区域裁剪到画面内并限制最大放大倍数，完整画面返回零值
+func (r ZoomRegion) Normalize() ZoomRegion {
+	if r.Full() {
+		return ZoomRegion{}
+	}
+	q := func(v float64) float64 { return math.Round(v*zoomQuantum) / zoomQuantum }
+	w := q(min(max(r.W, minZoomSize), 1))
+	h := q(min(max(r.H, minZoomSize), 1))
+	return ZoomRegion{
+		X: q(min(max(r.X, 0), 1-w)),
+		Y: q(min(max(r.Y, 0), 1-h)),
+		W: w,
+		H: h,
+	}
+}
+
+// Factor 返回放大倍数（宽高中较大的一个），完整画面为 1
+func (r ZoomRegion) Factor() float64 {
+	if r.Full() {
+		return 1
+	}
+	return max(1/r.W, 1/r.H)
+}
+
+// Map 把区域内的归一化坐标换算为完整画面中的归一化坐标
+func (r ZoomRegion) Map(x, y float64) (float64, float64) {
+	if r.Full() {
+		return x, y
+	}
+	return r.X + x*r.W, r.Y + y*r.H
+}
+
+// FullArea 返回完整画面在 view 所在坐标系中的位置，view 为显示本区域的矩形。
+// 结果通常大于 view，可直接作为 annotate.Renderer.Render 的 area。
+func (r ZoomRegion) FullArea(view image.Rectangle) image.Rectangle {
+	if r.Full() {
+		return view
+	}
+	w, h := float64(view.Dx())/r.W, float64(view.Dy())/r.H
+	x0 := float64(view.Min.X) - r.X*w
+	y0 := float64(view.Min.Y) - r.Y*h
+	return image.Rect(int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x0+w)), int(math.Round(y0+h)))
+}
+
+// pixelRect 返回区域在 b 中对应的像素矩形，至少 1×1
+func (r ZoomRegion) pixelRect(b image.Rectangle) image.Rectangle {
+	if r.Full() {
+		return b
+	}
+	x0 := b.Min.X + int(math.Round(r.X*float64(b.Dx())))
+	y0 := b.Min.Y + int(math.Round(r.Y*float64(b.Dy())))
+	x1 := b.Min.X + int(math.Round((r.X+r.W)*float64(b.Dx())))
+	y1 := b.Min.Y + int(math.Round((r.Y+r.H)*float64(b.Dy())))
+	return image.Rect(x0, y0, max(x1, x0+1), max(y1, y0+1)).Intersect(b)
+}
+
+// handleZoom 记录 Viewer 请求的区域，从下一帧起按该区域为其单独编码
+func (s *Publisher) handleZoom(peerID string, data json.RawMessage) {
+	var r ZoomRegion
+	if err := json.Unmarshal(data, &r); err != nil {
+		log.Println("publisher parse zoom from", peerID, "error:", err)
+		return
+	}
+	r = r.Normalize()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if peer, ok := s.peers[peerID]; ok {
+		peer.zoom = r
+	}
+}
+
+// -------------------- Viewer 侧 --------------------
+
+// SetZoom 请求只观看画面中的 r 区域（零值恢复完整画面），Publisher 会按原始分辨率裁出该区域发送。
+// 实际显示的区域以收到的帧为准，变化时通过 ViewerConfig.OnZoom 回调。
+func (v *Viewer) SetZoom(r ZoomRegion) error {
+	s := v.s
+	s.mu.Lock()
+	ctrl := s.ctrl
+	s.mu.Unlock()
+	if ctrl == nil || ctrl.ReadyState() != webrtc.DataChannelStateOpen {
+		return errors.New("control 通道尚未就绪")
+	}
+	return sendControl(ctrl, controlTypeZoom, r.Normalize())
+}
+
+// updateZoom 在收到的帧所显示的区域变化时回调 OnZoom
+func (s *viewerSession) updateZoom(r ZoomRegion) {
+	s.mu.Lock()
+	changed := s.zoom != r
+	s.zoom = r
+	s.mu.Unlock()
+	if changed && s.onZoom != nil {
+		s.onZoom(r)
+	}
+}
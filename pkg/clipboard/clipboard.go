@@ -0,0 +1,93 @@
+// Package clipboard 定义 Publisher 与 Viewer 之间共享剪贴板时读写本机剪贴板的接口，
+// 以及供测试使用的内存实现；基于 fyne 的实现在 internal/app 中
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // 注册 JPEG 解码，供 Check 识别图片
+	_ "image/png"
+	"unicode/utf8"
+)
+
+// Content 为一次剪贴板传输的内容，Text 与 Image 二选一。
+// Image 为编码后的 PNG / JPEG 数据，保持原始字节以免重复编码。
+type Content struct {
+	Text  string
+	Image []byte
+}
+
+// IsImage 返回内容是否为图片
+func (c Content) IsImage() bool {
+	return len(c.Image) > 0
+}
+
+// Size 返回内容的字节数
+func (c Content) Size() int {
+	if c.IsImage() {
+		return len(c.Image)
+	}
+	return len(c.Text)
+}
+
+// 默认大小上限
+const (
+	DefaultMaxText  = 64 << 10 // 64 KB 文本足够容纳命令、URL 和代码片段
+	DefaultMaxImage = 4 << 20  // 4 MB 图片
+)
+
+// Limits 限制单次传输的大小，零值字段使用默认值
+type Limits struct {
+	MaxText  int
+	MaxImage int
+}
+
+// Check 检查内容是否为空、是否超出上限，图片还会校验格式
+func (l Limits) Check(c Content) error {
+	maxText, maxImage := l.MaxText, l.MaxImage
+	if maxText <= 0 {
+		maxText = DefaultMaxText
+	}
+	if maxImage <= 0 {
+		maxImage = DefaultMaxImage
+	}
+	switch {
+	case c.IsImage():
+		if c.Text != "" {
+			return errors.New("文本与图片不能同时发送")
+		}
+		if len(c.Image) > maxImage {
+			return fmt.Errorf("图片过大: %s，上限 %s", formatSize(len(c.Image)), formatSize(maxImage))
+		}
+		if _, _, err := image.DecodeConfig(bytes.NewReader(c.Image)); err != nil {
+			return errors.New("不支持的图片格式，仅支持 PNG / JPEG")
+		}
+	case c.Text == "":
+		return errors.New("剪贴板为空")
+	case len(c.Text) > maxText:
+		return fmt.Errorf("文本过长: %s，上限 %s", formatSize(len(c.Text)), formatSize(maxText))
+	case !utf8.ValidString(c.Text):
+		return errors.New("文本不是有效的 UTF-8")
+	}
+	return nil
+}
+
+func formatSize(n int) string {
+	if n < 1<<10 {
+		return fmt.Sprintf("%d 字节", n)
+	}
+	return fmt.Sprintf("%d KB", n>>10)
+}
+
+// ErrImageUnsupported 表示该剪贴板实现无法读写图片，调用方可改为另存为文件
+var ErrImageUnsupported = errors.New("剪贴板不支持图片")
+
+// Clipboard 读写本机剪贴板
+type Clipboard interface {
+	Read() (Content, error)
+	Write(c Content) error
+}
+
+var _ Clipboard = (*Memory)(nil)
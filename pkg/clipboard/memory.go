@@ -0,0 +1,39 @@
+package clipboard
+
+import "sync"
+
+// Memory 是只存在于内存中的剪贴板，支持文本和图片，用于测试和无桌面环境
+type Memory struct {
+	mu      sync.Mutex
+	content Content
+	writes  int
+}
+
+// NewMemory 创建空的内存剪贴板
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Read 返回最近写入的内容
+func (m *Memory) Read() (Content, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.content, nil
+}
+
+// Write 覆盖当前内容
+func (m *Memory) Write(c Content) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c.Image = append([]byte(nil), c.Image...)
+	m.content = c
+	m.writes++
+	return nil
+}
+
+// Writes 返回累计写入次数
+func (m *Memory) Writes() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.writes
+}
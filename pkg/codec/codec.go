@@ -0,0 +1,132 @@
+// Package codec 定义帧编解码接口，以及 JPEG / PNG / QOI 三种实现
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+// ID 标识一种帧编码格式，会写入每一帧的帧头
+type ID byte
+
+const (
+	JPEG ID = 1 // 有损，体积小，默认
+	PNG  ID = 2 // 无损，压缩率高但编码较慢
+	QOI  ID = 3 // 无损，编码极快，体积介于两者之间
+)
+
+// DefaultJPEGQuality 为未指定质量时使用的 JPEG 质量
+const DefaultJPEGQuality = 60
+
+var names = map[ID]string{
+	JPEG: "jpeg",
+	PNG:  "png",
+	QOI:  "qoi",
+}
+
+func (id ID) String() string {
+	if n, ok := names[id]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+// ParseID 把 "jpeg" / "png" / "qoi" 解析为 ID，空字符串视为 JPEG
+func ParseID(name string) (ID, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "jpg" {
+		return JPEG, nil
+	}
+	for id, n := range names {
+		if n == name {
+			return id, nil
+		}
+	}
+	return 0, errors.New("未知的编码格式: " + name)
+}
+
+// Supported 返回本端支持的全部编码，按偏好从高到低排列
+func Supported() []ID {
+	return []ID{QOI, PNG, JPEG}
+}
+
+// Encoder 把一帧图像编码为字节流
+type Encoder interface {
+	ID() ID
+	Encode(img *image.RGBA) ([]byte, error)
+}
+
+// Decoder 把字节流解码为图像
+type Decoder interface {
+	ID() ID
+	Decode(data []byte) (image.Image, error)
+}
+
+// NewEncoder 创建指定格式的编码器，quality 仅对 JPEG 生效（非正时使用默认值）
+func NewEncoder(id ID, quality int) (Encoder, error) {
+	switch id {
+	case JPEG:
+		if quality <= 0 || quality > 100 {
+			quality = DefaultJPEGQuality
+		}
+		return jpegCodec{quality: quality}, nil
+	case PNG:
+		return pngCodec{enc: &png.Encoder{CompressionLevel: png.BestSpeed}}, nil
+	case QOI:
+		return qoiCodec{}, nil
+	}
+	return nil, errors.New("不支持的编码格式: " + id.String())
+}
+
+// NewDecoder 创建指定格式的解码器
+func NewDecoder(id ID) (Decoder, error) {
+	switch id {
+	case JPEG:
+		return jpegCodec{}, nil
+	case PNG:
+		return pngCodec{}, nil
+	case QOI:
+		return qoiCodec{}, nil
+	}
+	return nil, errors.New("不支持的编码格式: " + id.String())
+}
+
+type jpegCodec struct {
+	quality int
+}
+
+func (jpegCodec) ID() ID { return JPEG }
+
+func (c jpegCodec) Encode(img *image.RGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: c.quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (jpegCodec) Decode(data []byte) (image.Image, error) {
+	return jpeg.Decode(bytes.NewReader(data))
+}
+
+type pngCodec struct {
+	enc *png.Encoder
+}
+
+func (pngCodec) ID() ID { return PNG }
+
+func (c pngCodec) Encode(img *image.RGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.enc.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (pngCodec) Decode(data []byte) (image.Image, error) {
+	return png.Decode(bytes.NewReader(data))
+}
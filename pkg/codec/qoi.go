@@ -0,0 +1,183 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+// QOI（Quite OK Image）格式的纯 Go 实现，规范见 https://qoiformat.org/qoi-specification.pdf 。
+// 编码只需单次线性扫描，速度远快于 PNG，适合对文字清晰度要求高的屏幕内容。
+
+const (
+	qoiOpIndex = 0x00
+	qoiOpDiff  = 0x40
+	qoiOpLuma  = 0x80
+	qoiOpRun   = 0xc0
+	qoiOpRGB   = 0xfe
+	qoiOpRGBA  = 0xff
+	qoiMask2   = 0xc0
+
+	qoiHeaderSize = 14
+	// 解码时的像素上限（8192×8192，约 256 MB），帧头中的尺寸由对端决定，不能任其分配内存
+	qoiMaxPixels = 8192 * 8192
+	// 一个字节（QOI_OP_RUN）最多表示 62 个像素
+	qoiMaxRun = 62
+)
+
+var (
+	qoiMagic   = []byte("qoif")
+	qoiPadding = []byte{0, 0, 0, 0, 0, 0, 0, 1}
+)
+
+type qoiCodec struct{}
+
+func (qoiCodec) ID() ID { return QOI }
+
+type qoiPixel struct{ r, g, b, a byte }
+
+func (p qoiPixel) hash() int {
+	return (int(p.r)*3 + int(p.g)*5 + int(p.b)*7 + int(p.a)*11) % 64
+}
+
+// Encode 按 RGBA 四通道编码；屏幕帧均为不透明像素，预乘与否没有差别
+func (qoiCodec) Encode(img *image.RGBA) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("qoi: empty image")
+	}
+
+	// 预估容量：屏幕内容通常压缩到原始大小的 1/4 以内
+	out := make([]byte, 0, qoiHeaderSize+w*h+len(qoiPadding))
+	out = append(out, qoiMagic...)
+	out = binary.BigEndian.AppendUint32(out, uint32(w))
+	out = binary.BigEndian.AppendUint32(out, uint32(h))
+	out = append(out, 4, 0) // channels=4, colorspace=sRGB
+
+	var index [64]qoiPixel
+	prev := qoiPixel{a: 255}
+	run := 0
+	total := w * h
+	n := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < w; x++ {
+			i := x * 4
+			px := qoiPixel{row[i], row[i+1], row[i+2], row[i+3]}
+			n++
+
+			if px == prev {
+				run++
+				if run == qoiMaxRun || n == total {
+					out = append(out, qoiOpRun|byte(run-1))
+					run = 0
+				}
+				continue
+			}
+			if run > 0 {
+				out = append(out, qoiOpRun|byte(run-1))
+				run = 0
+			}
+
+			h := px.hash()
+			if index[h] == px {
+				out = append(out, qoiOpIndex|byte(h))
+				prev = px
+				continue
+			}
+			index[h] = px
+
+			if px.a != prev.a {
+				out = append(out, qoiOpRGBA, px.r, px.g, px.b, px.a)
+				prev = px
+				continue
+			}
+			vr := int8(px.r - prev.r)
+			vg := int8(px.g - prev.g)
+			vb := int8(px.b - prev.b)
+			vgr := vr - vg
+			vgb := vb - vg
+			switch {
+			case vr > -3 && vr < 2 && vg > -3 && vg < 2 && vb > -3 && vb < 2:
+				out = append(out, qoiOpDiff|byte(vr+2)<<4|byte(vg+2)<<2|byte(vb+2))
+			case vgr > -9 && vgr < 8 && vg > -33 && vg < 32 && vgb > -9 && vgb < 8:
+				out = append(out, qoiOpLuma|byte(vg+32), byte(vgr+8)<<4|byte(vgb+8))
+			default:
+				out = append(out, qoiOpRGB, px.r, px.g, px.b)
+			}
+			prev = px
+		}
+	}
+	return append(out, qoiPadding...), nil
+}
+
+// Decode 解码为 NRGBA 图像
+func (qoiCodec) Decode(data []byte) (image.Image, error) {
+	if len(data) < qoiHeaderSize+len(qoiPadding) || string(data[:4]) != string(qoiMagic) {
+		return nil, errors.New("qoi: invalid header")
+	}
+	w := int(binary.BigEndian.Uint32(data[4:8]))
+	h := int(binary.BigEndian.Uint32(data[8:12]))
+	if w <= 0 || h <= 0 || w*h > qoiMaxPixels {
+		return nil, errors.New("qoi: invalid dimensions")
+	}
+	// 数据量不足以编码这么多像素时不必分配
+	if w*h > (len(data)-qoiHeaderSize-len(qoiPadding))*qoiMaxRun {
+		return nil, errors.New("qoi: truncated data")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	pix := img.Pix
+	var index [64]qoiPixel
+	px := qoiPixel{a: 255}
+	p := qoiHeaderSize
+	end := len(data) - len(qoiPadding)
+	run := 0
+
+	for o := 0; o < len(pix); o += 4 {
+		if run > 0 {
+			run--
+		} else if p < end {
+			op := data[p]
+			p++
+			switch {
+			case op == qoiOpRGB:
+				if p+3 > end {
+					return nil, errors.New("qoi: truncated data")
+				}
+				px.r, px.g, px.b = data[p], data[p+1], data[p+2]
+				p += 3
+			case op == qoiOpRGBA:
+				if p+4 > end {
+					return nil, errors.New("qoi: truncated data")
+				}
+				px = qoiPixel{data[p], data[p+1], data[p+2], data[p+3]}
+				p += 4
+			case op&qoiMask2 == qoiOpIndex:
+				px = index[op]
+			case op&qoiMask2 == qoiOpDiff:
+				px.r += (op>>4)&0x03 - 2
+				px.g += (op>>2)&0x03 - 2
+				px.b += op&0x03 - 2
+			case op&qoiMask2 == qoiOpLuma:
+				if p >= end {
+					return nil, errors.New("qoi: truncated data")
+				}
+				b2 := data[p]
+				p++
+				vg := (op & 0x3f) - 32
+				px.r += vg - 8 + (b2>>4)&0x0f
+				px.g += vg
+				px.b += vg - 8 + b2&0x0f
+			case op&qoiMask2 == qoiOpRun:
+				run = int(op & 0x3f)
+			}
+			index[px.hash()] = px
+		} else {
+			return nil, errors.New("qoi: truncated data")
+		}
+		pix[o], pix[o+1], pix[o+2], pix[o+3] = px.r, px.g, px.b, px.a
+	}
+	return img, nil
+}
@@ -0,0 +1,51 @@
+// Package input 定义远程控制时在 Publisher 本机注入键鼠事件的接口及其实现
+package input
+
+import "errors"
+
+// EventType 为输入事件类型
+type EventType string
+
+const (
+	MouseMove EventType = "move"
+	MouseDown EventType = "down"
+	MouseUp   EventType = "up"
+	Scroll    EventType = "scroll"
+	KeyDown   EventType = "key_down"
+	KeyUp     EventType = "key_up"
+)
+
+// MouseButton 采用 X11 的按键编号
+type MouseButton int
+
+const (
+	ButtonLeft   MouseButton = 1
+	ButtonMiddle MouseButton = 2
+	ButtonRight  MouseButton = 3
+)
+
+// Event 是一次要注入的输入事件。
+// X / Y 为桌面绝对坐标（多屏时相对主屏左上角），鼠标类事件使用；
+// DX / DY 为滚轮格数，正值向右 / 向下；
+// Key 为按键名，取值与 fyne.KeyName 一致（如 "A"、"Return"、"LeftShift"）。
+type Event struct {
+	Type   EventType
+	X, Y   int
+	Button MouseButton
+	DX, DY int
+	Key    string
+}
+
+// ErrUnsupportedKey 表示注入器不认识该按键名
+var ErrUnsupportedKey = errors.New("不支持的按键")
+
+// InputInjector 把输入事件注入到本机桌面
+type InputInjector interface {
+	Inject(ev Event) error
+	Close() error
+}
+
+var (
+	_ InputInjector = (*RecordingInjector)(nil)
+	_ InputInjector = (*XTestInjector)(nil)
+)
@@ -0,0 +1,51 @@
+package input
+
+import (
+	"errors"
+	"sync"
+)
+
+// RecordingInjector 只记录事件、不做任何实际注入，用于测试和无桌面环境下的演示
+type RecordingInjector struct {
+	mu     sync.Mutex
+	events []Event
+	closed bool
+}
+
+// NewRecordingInjector 创建空的记录器
+func NewRecordingInjector() *RecordingInjector {
+	return &RecordingInjector{}
+}
+
+// Inject 记录事件
+func (r *RecordingInjector) Inject(ev Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errors.New("injector 已关闭")
+	}
+	r.events = append(r.events, ev)
+	return nil
+}
+
+// Events 返回目前记录到的所有事件
+func (r *RecordingInjector) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Reset 清空已记录的事件
+func (r *RecordingInjector) Reset() {
+	r.mu.Lock()
+	r.events = nil
+	r.mu.Unlock()
+}
+
+// Close 之后再注入会返回错误
+func (r *RecordingInjector) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,202 @@
+package input
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+)
+
+// 按键名（与 fyne.KeyName 一致）到 X11 keysym 的映射，字母和数字在 keysym() 中单独处理
+var namedKeysyms = map[string]xproto.Keysym{
+	"Escape":       0xff1b,
+	"Return":       0xff0d,
+	"Tab":          0xff09,
+	"BackSpace":    0xff08,
+	"Insert":       0xff63,
+	"Delete":       0xffff,
+	"Right":        0xff53,
+	"Left":         0xff51,
+	"Down":         0xff54,
+	"Up":           0xff52,
+	"Prior":        0xff55,
+	"Next":         0xff56,
+	"Home":         0xff50,
+	"End":          0xff57,
+	"KP_Enter":     0xff8d,
+	"Space":        0x0020,
+	"'":            0x0027,
+	",":            0x002c,
+	"-":            0x002d,
+	".":            0x002e,
+	"/":            0x002f,
+	"\\":           0x005c,
+	"[":            0x005b,
+	"]":            0x005d,
+	";":            0x003b,
+	"=":            0x003d,
+	"*":            0xffaa,
+	"+":            0xffab,
+	"`":            0x0060,
+	"LeftShift":    0xffe1,
+	"RightShift":   0xffe2,
+	"LeftControl":  0xffe3,
+	"RightControl": 0xffe4,
+	"LeftAlt":      0xffe9,
+	"RightAlt":     0xffea,
+	"LeftSuper":    0xffeb,
+	"RightSuper":   0xffec,
+	"Menu":         0xff67,
+	"PrintScreen":  0xff61,
+	"CapsLock":     0xffe5,
+}
+
+func keysym(name string) (xproto.Keysym, bool) {
+	if len(name) == 1 {
+		c := name[0]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			// 注入的是物理按键，大小写由 Shift 事件决定，这里统一用小写 keysym
+			return xproto.Keysym(c - 'A' + 'a'), true
+		case c >= '0' && c <= '9':
+			return xproto.Keysym(c), true
+		}
+	}
+	if len(name) >= 2 && len(name) <= 3 && name[0] == 'F' {
+		n := 0
+		for _, c := range name[1:] {
+			if c < '0' || c > '9' {
+				n = 0
+				break
+			}
+			n = n*10 + int(c-'0')
+		}
+		if n >= 1 && n <= 12 {
+			return xproto.Keysym(0xffbe + n - 1), true
+		}
+	}
+	ks, ok := namedKeysyms[name]
+	return ks, ok
+}
+
+// XTestInjector 通过 X11 XTest 扩展注入事件，需要在 X11（或 XWayland）会话中运行
+type XTestInjector struct {
+	mu      sync.Mutex
+	conn    *xgb.Conn
+	root    xproto.Window
+	keycode map[xproto.Keysym]xproto.Keycode
+}
+
+// NewXTestInjector 连接 $DISPLAY 并初始化 XTest 扩展
+func NewXTestInjector() (*XTestInjector, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, err
+	}
+	if err := xtest.Init(conn); err != nil {
+		conn.Close()
+		return nil, errors.New("X 服务器不支持 XTest 扩展: " + err.Error())
+	}
+	setup := xproto.Setup(conn)
+	inj := &XTestInjector{
+		conn:    conn,
+		root:    setup.DefaultScreen(conn).Root,
+		keycode: make(map[xproto.Keysym]xproto.Keycode),
+	}
+
+	// 预先建立 keysym → keycode 表，注入时无需再往返 X 服务器
+	first, last := setup.MinKeycode, setup.MaxKeycode
+	reply, err := xproto.GetKeyboardMapping(conn, first, byte(last-first+1)).Reply()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	per := int(reply.KeysymsPerKeycode)
+	for i := 0; i*per < len(reply.Keysyms); i++ {
+		kc := first + xproto.Keycode(i)
+		// 只看前两列（无修饰 / Shift），先出现的 keycode 优先
+		for j := 0; j < per && j < 2; j++ {
+			ks := reply.Keysyms[i*per+j]
+			if _, exists := inj.keycode[ks]; ks != 0 && !exists {
+				inj.keycode[ks] = kc
+			}
+		}
+	}
+	return inj, nil
+}
+
+// Inject 注入一个事件
+func (x *XTestInjector) Inject(ev Event) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.conn == nil {
+		return errors.New("injector 已关闭")
+	}
+	switch ev.Type {
+	case MouseMove:
+		return x.fake(xproto.MotionNotify, 0, ev.X, ev.Y)
+	case MouseDown, MouseUp:
+		if err := x.fake(xproto.MotionNotify, 0, ev.X, ev.Y); err != nil {
+			return err
+		}
+		typ := byte(xproto.ButtonPress)
+		if ev.Type == MouseUp {
+			typ = xproto.ButtonRelease
+		}
+		return x.fake(typ, byte(ev.Button), 0, 0)
+	case Scroll:
+		// X11 中滚轮是按键 4/5（上/下）和 6/7（左/右），每格一次按下 + 抬起
+		if err := x.clickRepeat(4, 5, ev.DY); err != nil {
+			return err
+		}
+		return x.clickRepeat(6, 7, ev.DX)
+	case KeyDown, KeyUp:
+		ks, ok := keysym(ev.Key)
+		if !ok {
+			return ErrUnsupportedKey
+		}
+		kc, ok := x.keycode[ks]
+		if !ok {
+			return ErrUnsupportedKey
+		}
+		typ := byte(xproto.KeyPress)
+		if ev.Type == KeyUp {
+			typ = xproto.KeyRelease
+		}
+		return x.fake(typ, byte(kc), 0, 0)
+	}
+	return errors.New("未知的输入事件类型: " + string(ev.Type))
+}
+
+func (x *XTestInjector) clickRepeat(negative, positive byte, steps int) error {
+	button := positive
+	if steps < 0 {
+		button, steps = negative, -steps
+	}
+	for i := 0; i < steps; i++ {
+		if err := x.fake(xproto.ButtonPress, button, 0, 0); err != nil {
+			return err
+		}
+		if err := x.fake(xproto.ButtonRelease, button, 0, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *XTestInjector) fake(typ, detail byte, rootX, rootY int) error {
+	return xtest.FakeInputChecked(x.conn, typ, detail, 0, x.root, int16(rootX), int16(rootY), 0).Check()
+}
+
+// Close 断开与 X 服务器的连接
+func (x *XTestInjector) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.conn != nil {
+		x.conn.Close()
+		x.conn = nil
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package input
+
+import "errors"
+
+// XTestInjector 仅在 Linux 上可用
+type XTestInjector struct{}
+
+// NewXTestInjector 在非 Linux 平台上总是返回错误
+func NewXTestInjector() (*XTestInjector, error) {
+	return nil, errors.New("当前平台不支持 XTest 输入注入")
+}
+
+func (*XTestInjector) Inject(Event) error {
+	return errors.New("当前平台不支持 XTest 输入注入")
+}
+
+func (*XTestInjector) Close() error { return nil }
@@ -0,0 +1,309 @@
+// Package overlay 在编码前把水印、时间戳、横幅、Logo 等叠加到帧上
+package overlay
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"sync"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Anchor 表示叠加元素在画面中的锚点
+type Anchor string
+
+const (
+	TopLeft      Anchor = "top-left"
+	TopCenter    Anchor = "top-center"
+	TopRight     Anchor = "top-right"
+	Center       Anchor = "center"
+	BottomLeft   Anchor = "bottom-left"
+	BottomCenter Anchor = "bottom-center"
+	BottomRight  Anchor = "bottom-right"
+)
+
+// 文字中可用的占位符
+const (
+	PlaceholderName = "{name}" // 替换为 Config.Name
+	PlaceholderTime = "{time}" // 替换为当前时间
+)
+
+// Element 描述一个叠加元素，Text 与 Image 二选一（都设置时只绘制图片）。
+// 尺寸、边距均按输出画面高度的比例给出，因此在任意输出分辨率下都保持相同的观感。
+type Element struct {
+	Text  string
+	Image image.Image
+
+	Anchor Anchor
+	// Size 为文字行高或图片高度占画面高度的比例，默认 0.03
+	Size float64
+	// Margin 为到画面边缘的距离占画面高度的比例，默认 0.015
+	Margin float64
+	// Opacity 为整体不透明度 0~1，0 视为 1
+	Opacity float64
+
+	Color      color.Color // 文字颜色，默认白色
+	Background color.Color // 文字底色，nil 表示无底色
+	// Banner 为 true 时底色横跨整个画面宽度，用于 "CONFIDENTIAL" 一类横幅
+	Banner bool
+}
+
+// Config 是一组叠加元素及其公共参数
+type Config struct {
+	// Name 用于替换文字中的 {name}，通常为推流者名称
+	Name string
+	// TimeFormat 用于替换文字中的 {time}，默认 "2006-01-02 15:04:05"
+	TimeFormat string
+	Elements   []Element
+}
+
+// Enabled 返回是否配置了任何叠加元素
+func (c Config) Enabled() bool {
+	return len(c.Elements) > 0
+}
+
+// Confidential 返回一个常用的顶部 "CONFIDENTIAL" 红色横幅
+func Confidential() Element {
+	return Element{
+		Text:       "CONFIDENTIAL",
+		Anchor:     TopCenter,
+		Size:       0.04,
+		Margin:     0,
+		Color:      color.White,
+		Background: color.RGBA{R: 0xc0, A: 0xc0},
+		Banner:     true,
+	}
+}
+
+// Timestamp 返回右下角的 "{name} {time}" 水印
+func Timestamp() Element {
+	return Element{
+		Text:       PlaceholderName + "  " + PlaceholderTime,
+		Anchor:     BottomRight,
+		Background: color.RGBA{A: 0x80},
+	}
+}
+
+const (
+	defaultSize       = 0.03
+	defaultMargin     = 0.015
+	defaultTimeFormat = "2006-01-02 15:04:05"
+	minFontPixels     = 10
+	// 缩放后图片的缓存上限；放大区域的画面高度各不相同，超出后清空重来
+	maxScaledImages = 32
+)
+
+// Renderer 负责把 Config 绘制到帧上，内部按像素大小缓存字体，按目标高度缓存缩放后的图片。
+// 字体对象不支持并发绘制，同一个 Renderer 应只在一个 goroutine 中调用 Apply。
+// 元素在创建时确定，之后不再变化，因此缓存不需要失效。
+type Renderer struct {
+	cfg  Config
+	font *opentype.Font
+
+	mu     sync.Mutex
+	faces  map[int]font.Face
+	images map[imageKey]*image.RGBA
+}
+
+// imageKey 为缩放后图片的缓存键：第几个元素、缩放到多少像素高
+type imageKey struct {
+	index  int
+	height int
+}
+
+// NewRenderer 创建叠加渲染器
+func NewRenderer(cfg Config) (*Renderer, error) {
+	f, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TimeFormat == "" {
+		cfg.TimeFormat = defaultTimeFormat
+	}
+	return &Renderer{
+		cfg:    cfg,
+		font:   f,
+		faces:  make(map[int]font.Face),
+		images: make(map[imageKey]*image.RGBA),
+	}, nil
+}
+
+// Apply 把所有元素就地绘制到 dst 上，now 用于替换 {time}
+func (r *Renderer) Apply(dst *image.RGBA, now time.Time) {
+	if r == nil || dst == nil {
+		return
+	}
+	for i, el := range r.cfg.Elements {
+		if el.Image != nil {
+			r.drawImage(dst, i, el)
+		} else if el.Text != "" {
+			r.drawText(dst, el, now)
+		}
+	}
+}
+
+// DrawText 按 el 的样式绘制任意文字（忽略 el.Text），供需要逐帧变化文字的调用方使用
+func (r *Renderer) DrawText(dst *image.RGBA, el Element, text string) {
+	if r == nil || dst == nil || text == "" {
+		return
+	}
+	el.Text = text
+	el.Image = nil
+	r.drawText(dst, el, time.Time{})
+}
+
+func (r *Renderer) expand(text string, now time.Time) string {
+	text = strings.ReplaceAll(text, PlaceholderName, r.cfg.Name)
+	if strings.Contains(text, PlaceholderTime) {
+		text = strings.ReplaceAll(text, PlaceholderTime, now.Format(r.cfg.TimeFormat))
+	}
+	return text
+}
+
+func (r *Renderer) face(px int) (font.Face, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.faces[px]; ok {
+		return f, nil
+	}
+	f, err := opentype.NewFace(r.font, &opentype.FaceOptions{
+		Size:    float64(px),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.faces[px] = f
+	return f, nil
+}
+
+func (r *Renderer) drawText(dst *image.RGBA, el Element, now time.Time) {
+	b := dst.Bounds()
+	px := max(int(float64(b.Dy())*orDefault(el.Size, defaultSize)), minFontPixels)
+	face, err := r.face(px)
+	if err != nil {
+		return
+	}
+	text := r.expand(el.Text, now)
+
+	m := face.Metrics()
+	tw := font.MeasureString(face, text).Ceil()
+	th := (m.Ascent + m.Descent).Ceil()
+	pad := px / 4
+
+	box := image.Rect(0, 0, tw+2*pad, th+2*pad)
+	pos := place(b, box.Size(), el.Anchor, margin(b, el))
+	box = box.Add(pos)
+	if el.Banner {
+		box.Min.X, box.Max.X = b.Min.X, b.Max.X
+	}
+	alpha := opacityMask(el.Opacity)
+
+	if el.Background != nil {
+		draw.DrawMask(dst, box, image.NewUniform(el.Background), image.Point{}, alpha, image.Point{}, draw.Over)
+	}
+
+	textColor := el.Color
+	if textColor == nil {
+		textColor = color.White
+	}
+	// 先画到透明图层再按不透明度合成，避免直接写入时丢失半透明效果
+	layer := image.NewRGBA(image.Rect(0, 0, tw, th))
+	d := font.Drawer{
+		Dst:  layer,
+		Src:  image.NewUniform(textColor),
+		Face: face,
+		Dot:  fixed.P(0, m.Ascent.Ceil()),
+	}
+	d.DrawString(text)
+
+	textPos := image.Pt(box.Min.X+(box.Dx()-tw)/2, box.Min.Y+pad)
+	draw.DrawMask(dst, layer.Bounds().Add(textPos), layer, image.Point{}, alpha, image.Point{}, draw.Over)
+}
+
+// scaledImage 返回第 index 个元素的图片缩放到 h 像素高的结果；CatmullRom 缩放开销较大，不逐帧重复
+func (r *Renderer) scaledImage(index int, img image.Image, h int) *image.RGBA {
+	key := imageKey{index: index, height: h}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if scaled, ok := r.images[key]; ok {
+		return scaled
+	}
+	src := img.Bounds()
+	w := max(src.Dx()*h/src.Dy(), 1)
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), img, src, xdraw.Src, nil)
+	if len(r.images) >= maxScaledImages {
+		clear(r.images)
+	}
+	r.images[key] = scaled
+	return scaled
+}
+
+func (r *Renderer) drawImage(dst *image.RGBA, index int, el Element) {
+	b := dst.Bounds()
+	if el.Image.Bounds().Empty() {
+		return
+	}
+	h := max(int(float64(b.Dy())*orDefault(el.Size, defaultSize)), 1)
+	scaled := r.scaledImage(index, el.Image, h)
+
+	pos := place(b, scaled.Bounds().Size(), el.Anchor, margin(b, el))
+	draw.DrawMask(dst, scaled.Bounds().Add(pos), scaled, image.Point{}, opacityMask(el.Opacity), image.Point{}, draw.Over)
+}
+
+// place 计算尺寸为 size 的元素按锚点摆放后的左上角坐标
+func place(b image.Rectangle, size image.Point, anchor Anchor, margin int) image.Point {
+	left := b.Min.X + margin
+	centerX := b.Min.X + (b.Dx()-size.X)/2
+	right := b.Max.X - margin - size.X
+	top := b.Min.Y + margin
+	centerY := b.Min.Y + (b.Dy()-size.Y)/2
+	bottom := b.Max.Y - margin - size.Y
+
+	switch anchor {
+	case TopLeft:
+		return image.Pt(left, top)
+	case TopCenter:
+		return image.Pt(centerX, top)
+	case TopRight:
+		return image.Pt(right, top)
+	case Center:
+		return image.Pt(centerX, centerY)
+	case BottomLeft:
+		return image.Pt(left, bottom)
+	case BottomCenter:
+		return image.Pt(centerX, bottom)
+	default:
+		return image.Pt(right, bottom)
+	}
+}
+
+func margin(b image.Rectangle, el Element) int {
+	if el.Banner && el.Margin == 0 {
+		return 0
+	}
+	return int(float64(b.Dy()) * orDefault(el.Margin, defaultMargin))
+}
+
+func opacityMask(opacity float64) image.Image {
+	if opacity <= 0 || opacity >= 1 {
+		return image.Opaque
+	}
+	return image.NewUniform(color.Alpha{A: uint8(opacity * 255)})
+}
+
+func orDefault(v, def float64) float64 {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -4,6 +4,7 @@ import (
 	"image"
 	"log"
 	"strconv"
+	"sync"
 
 	"github.com/kbinani/screenshot"
 	xdraw "golang.org/x/image/draw"
@@ -18,7 +19,10 @@ type DisplayInfo struct {
 
 type Capture struct {
 	screenIndex int
-	region      *image.Rectangle
+
+	mu     sync.RWMutex
+	region *image.Rectangle
+	masks  []Mask
 }
 
 // NewCapture 创建屏幕捕获实例
@@ -28,9 +32,26 @@ func NewCapture(index int) *Capture {
 
 // SetRegion 设置捕获区域（相对屏幕左上角），nil 表示整个屏幕
 func (c *Capture) SetRegion(region *image.Rectangle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.region = region
 }
 
+// SetMasks 设置遮挡区域，可在推流过程中随时修改，下一帧即生效。
+// 遮挡在 CaptureFrame 内部完成，之后的缩放、编码、录制等环节拿到的都是已遮挡的画面。
+func (c *Capture) SetMasks(masks []Mask) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.masks = append([]Mask(nil), masks...)
+}
+
+// Masks 返回当前的遮挡区域
+func (c *Capture) Masks() []Mask {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Mask(nil), c.masks...)
+}
+
 // ListDisplays 列出所有可用屏幕
 func ListDisplays() []DisplayInfo {
 	n := screenshot.NumActiveDisplays()
@@ -53,10 +74,15 @@ func (c *Capture) CaptureFrame() *image.RGBA {
 		log.Printf("屏幕索引 %d 超出范围", c.screenIndex)
 		return nil
 	}
+	c.mu.RLock()
+	region := c.region
+	masks := c.masks
+	c.mu.RUnlock()
+
 	bounds := screenshot.GetDisplayBounds(c.screenIndex)
 	targetBounds := bounds
-	if c.region != nil {
-		crop := c.region.Add(bounds.Min)
+	if region != nil {
+		crop := region.Add(bounds.Min)
 		crop = crop.Intersect(bounds)
 		if crop.Empty() {
 			log.Println("捕获区域无效：超出屏幕范围")
@@ -70,15 +96,37 @@ func (c *Capture) CaptureFrame() *image.RGBA {
 		log.Println("捕获屏幕失败:", err)
 		return nil
 	}
+	ApplyMasks(img, masks)
 	return img
 }
 
+// ScreenBounds 返回当前捕获画面对应的桌面绝对区域（已考虑捕获区域），屏幕不存在时为空矩形
+func (c *Capture) ScreenBounds() image.Rectangle {
+	if c.screenIndex >= screenshot.NumActiveDisplays() {
+		return image.Rectangle{}
+	}
+	c.mu.RLock()
+	region := c.region
+	c.mu.RUnlock()
+
+	bounds := screenshot.GetDisplayBounds(c.screenIndex)
+	if region == nil {
+		return bounds
+	}
+	return region.Add(bounds.Min).Intersect(bounds)
+}
+
 // CaptureFrameSized 捕获当前屏幕帧，并缩放到目标分辨率
 func (c *Capture) CaptureFrameSized(width, height int) *image.RGBA {
 	frame := c.CaptureFrame()
 	if frame == nil {
 		return nil
 	}
+	return Scale(frame, width, height)
+}
+
+// Scale 将帧缩放到目标分辨率，宽高任一非正时原样返回
+func Scale(frame *image.RGBA, width, height int) *image.RGBA {
 	if width <= 0 || height <= 0 {
 		return frame
 	}
@@ -89,6 +137,28 @@ func (c *Capture) CaptureFrameSized(width, height int) *image.RGBA {
 	return dst
 }
 
+// Crop 复制 frame 中的 rect 区域，超过 maxWidth×maxHeight 时等比缩小到其中（不放大），
+// maxWidth / maxHeight 任一非正时保持原始分辨率
+func Crop(frame *image.RGBA, rect image.Rectangle, maxWidth, maxHeight int) *image.RGBA {
+	rect = rect.Intersect(frame.Bounds())
+	w, h := rect.Dx(), rect.Dy()
+	if w <= 0 || h <= 0 {
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	}
+	if maxWidth > 0 && maxHeight > 0 {
+		scale := min(1, float64(maxWidth)/float64(w), float64(maxHeight)/float64(h))
+		w, h = max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if w == rect.Dx() && h == rect.Dy() {
+		xdraw.Copy(dst, image.Point{}, frame, rect, xdraw.Src, nil)
+	} else {
+		xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), frame, rect, xdraw.Src, nil)
+	}
+	return dst
+}
+
 func displayLabel(index int, bounds image.Rectangle) string {
 	w := bounds.Dx()
 	h := bounds.Dy()
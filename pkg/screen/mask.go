@@ -0,0 +1,93 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// MaskStyle 决定遮挡区域的处理方式
+type MaskStyle string
+
+const (
+	MaskBlack    MaskStyle = "black"    // 纯黑覆盖
+	MaskPixelate MaskStyle = "pixelate" // 马赛克
+	MaskBlur     MaskStyle = "blur"     // 模糊
+)
+
+// Mask 描述一个需要在编码前遮挡的矩形区域，坐标相对捕获画面左上角。
+// Percent 为 true 时 X/Y/W/H 按捕获画面宽高的百分比（0~100）解释，否则为像素值。
+type Mask struct {
+	X, Y, W, H float64
+	Percent    bool
+	Style      MaskStyle
+}
+
+// Rect 将遮挡区域换算为 bounds 内的像素矩形
+func (m Mask) Rect(bounds image.Rectangle) image.Rectangle {
+	x, y, w, h := m.X, m.Y, m.W, m.H
+	if m.Percent {
+		fw, fh := float64(bounds.Dx()), float64(bounds.Dy())
+		x, y, w, h = x*fw/100, y*fh/100, w*fw/100, h*fh/100
+	}
+	r := image.Rect(int(x), int(y), int(x+w+0.5), int(y+h+0.5)).Add(bounds.Min)
+	return r.Intersect(bounds)
+}
+
+// ApplyMasks 就地把遮挡区域写入帧中
+func ApplyMasks(img *image.RGBA, masks []Mask) {
+	if img == nil {
+		return
+	}
+	for _, m := range masks {
+		r := m.Rect(img.Bounds())
+		if r.Empty() {
+			continue
+		}
+		switch m.Style {
+		case MaskPixelate:
+			pixelate(img, r)
+		case MaskBlur:
+			blur(img, r)
+		default:
+			draw.Draw(img, r, image.Black, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// pixelate 用块内平均色填充，块大小随区域尺寸变化，保证小区域也无法辨认
+func pixelate(img *image.RGBA, r image.Rectangle) {
+	block := max(r.Dx(), r.Dy()) / 12
+	if block < 8 {
+		block = 8
+	}
+	for by := r.Min.Y; by < r.Max.Y; by += block {
+		for bx := r.Min.X; bx < r.Max.X; bx += block {
+			cell := image.Rect(bx, by, bx+block, by+block).Intersect(r)
+			var sr, sg, sb, n uint32
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					c := img.RGBAAt(x, y)
+					sr += uint32(c.R)
+					sg += uint32(c.G)
+					sb += uint32(c.B)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			avg := color.RGBA{R: uint8(sr / n), G: uint8(sg / n), B: uint8(sb / n), A: 0xff}
+			draw.Draw(img, cell, image.NewUniform(avg), image.Point{}, draw.Src)
+		}
+	}
+}
+
+// blur 先把区域缩得很小再放大回去，得到平滑但无法辨认内容的画面
+func blur(img *image.RGBA, r image.Rectangle) {
+	small := image.NewRGBA(image.Rect(0, 0, max(r.Dx()/24, 1), max(r.Dy()/24, 1)))
+	xdraw.ApproxBiLinear.Scale(small, small.Bounds(), img, r, xdraw.Src, nil)
+	xdraw.BiLinear.Scale(img, r, small, small.Bounds(), xdraw.Src, nil)
+}
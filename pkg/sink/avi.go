@@ -0,0 +1,194 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"snap-screen/pkg/avi"
+	"snap-screen/pkg/client"
+)
+
+// DefaultAVIFrameRate 为 AVI 录制的默认名义帧率
+const DefaultAVIFrameRate = 30
+
+// AVIOptions 控制 AVI 录制
+type AVIOptions struct {
+	// Prefix 为文件名前缀，文件名为 <Prefix>-20060102-150405-001.avi，空时为 "snap-screen"
+	Prefix string
+	// FrameRate 为 AVI 的名义帧率，决定时间精度，零值为 30。
+	// 帧按收到的时间放进对应的位置，两帧之间的空缺在播放时保持上一帧
+	FrameRate int
+	// Quality 只在需要重新编码 JPEG 时使用，零值为默认质量
+	Quality int
+	// MaxSize / MaxDuration 为单个分段的上限，达到后开始写下一个文件，零值为不按该条件分段；
+	// 无论如何单个文件不超过 avi.MaxSize
+	MaxSize     int64
+	MaxDuration time.Duration
+	// OnSegment 在一个分段写完时回调，err 不为 nil 表示该分段收尾失败；
+	// 回调时持有内部锁，不能再调用 AVI 的方法
+	OnSegment func(path string, err error)
+}
+
+// AVI 把每一帧按收到的时间写入 dir 下的 Motion-JPEG AVI 文件，按大小、时长分段；
+// 画面尺寸变化（例如对方调整了分辨率或本端放大查看）时同样另起一段。
+// 以 JPEG 传输的帧原样写入，不会二次压缩。用完后必须调用 Close，否则最后一段没有索引。
+type AVI struct {
+	dir  string
+	opts AVIOptions
+
+	mu     sync.Mutex
+	f      *os.File
+	w      *avi.Writer
+	path   string
+	size   image.Point
+	start  time.Time
+	seq    int
+	files  []string
+	frames int
+	closed bool
+}
+
+// NewAVI 创建目录（已存在时沿用），收到第一帧时才创建文件
+func NewAVI(dir string, opts AVIOptions) (*AVI, error) {
+	if opts.FrameRate <= 0 {
+		opts.FrameRate = DefaultAVIFrameRate
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "snap-screen"
+	}
+	if opts.MaxSize < 0 || opts.MaxDuration < 0 {
+		return nil, errors.New("分段大小和时长不能为负数")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &AVI{dir: dir, opts: opts}, nil
+}
+
+// WriteFrame 实现 client.FrameSink；Close 之后收到的帧被忽略
+func (r *AVI) WriteFrame(f client.Frame) error {
+	data, err := encodeJPEG(f, r.opts.Quality)
+	if err != nil {
+		return err
+	}
+	at := f.Received
+	if at.IsZero() {
+		at = time.Now()
+	}
+	size := f.Image.Bounds().Size()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	if r.w != nil && r.needRotate(size, at) {
+		r.finishSegment()
+	}
+	if r.w == nil {
+		if err := r.startSegment(size, at); err != nil {
+			return err
+		}
+	}
+	ok, err := r.w.WriteFrame(data, at.Sub(r.start))
+	if errors.Is(err, avi.ErrTooLarge) {
+		r.finishSegment()
+		if err := r.startSegment(size, at); err != nil {
+			return err
+		}
+		ok, err = r.w.WriteFrame(data, 0)
+	}
+	if err != nil {
+		return err
+	}
+	if ok {
+		r.frames++
+	}
+	return nil
+}
+
+// needRotate 返回写入 at 时刻、尺寸为 size 的帧之前是否应另起一段
+func (r *AVI) needRotate(size image.Point, at time.Time) bool {
+	switch {
+	case size != r.size:
+		return true
+	case r.opts.MaxSize > 0 && r.w.Size() >= r.opts.MaxSize:
+		return true
+	case r.opts.MaxDuration > 0 && at.Sub(r.start) >= r.opts.MaxDuration:
+		return true
+	}
+	return false
+}
+
+// startSegment 以 at 为起点创建新的分段文件
+func (r *AVI) startSegment(size image.Point, at time.Time) error {
+	r.seq++
+	name := fmt.Sprintf("%s-%s-%03d.avi", r.opts.Prefix, at.Format("20060102-150405"), r.seq)
+	path := filepath.Join(r.dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w, err := avi.NewWriter(f, size.X, size.Y, r.opts.FrameRate)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	r.f, r.w, r.path, r.size, r.start = f, w, path, size, at
+	return nil
+}
+
+// finishSegment 写出当前分段的索引并关闭文件
+func (r *AVI) finishSegment() error {
+	if r.w == nil {
+		return nil
+	}
+	err := r.w.Close()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	r.files = append(r.files, r.path)
+	if r.opts.OnSegment != nil {
+		r.opts.OnSegment(r.path, err)
+	}
+	r.f, r.w, r.path = nil, nil, ""
+	return err
+}
+
+// Close 结束录制并写完最后一段，可重复调用
+func (r *AVI) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.finishSegment()
+}
+
+// Path 返回正在写入的文件，尚未收到画面或已关闭时为空
+func (r *AVI) Path() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.path
+}
+
+// Files 返回已写完的分段文件
+func (r *AVI) Files() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.files...)
+}
+
+// Count 返回已写入的帧数；与上一帧间隔不足一个时间槽而被丢弃的帧不计入
+func (r *AVI) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.frames
+}
@@ -0,0 +1,67 @@
+package sink
+
+import (
+	"sync"
+	"time"
+
+	"snap-screen/pkg/client"
+)
+
+// Counter 只统计收到的帧，不保存画面，用于测量吞吐和端到端测试
+type Counter struct {
+	mu      sync.Mutex
+	stats   CounterStats
+	lastSeq uint32
+}
+
+// CounterStats 为 Counter 的统计结果
+type CounterStats struct {
+	Frames int
+	// Skipped 为按帧序号推算出的、Publisher 没有发给本端的帧数
+	Skipped int
+	// Bytes 为帧解码后的像素字节数（按 RGBA 计算）
+	Bytes  int64
+	First  time.Time
+	Last   time.Time
+	Width  int
+	Height int
+}
+
+// FPS 返回首帧到末帧之间的平均帧率
+func (s CounterStats) FPS() float64 {
+	d := s.Last.Sub(s.First)
+	if s.Frames < 2 || d <= 0 {
+		return 0
+	}
+	return float64(s.Frames-1) / d.Seconds()
+}
+
+// NewCounter 创建计数器
+func NewCounter() *Counter {
+	return &Counter{}
+}
+
+// WriteFrame 实现 client.FrameSink
+func (c *Counter) WriteFrame(f client.Frame) error {
+	b := f.Image.Bounds()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stats.Frames == 0 {
+		c.stats.First = f.Received
+	} else if f.Seq > c.lastSeq+1 {
+		c.stats.Skipped += int(f.Seq - c.lastSeq - 1)
+	}
+	c.lastSeq = f.Seq
+	c.stats.Frames++
+	c.stats.Bytes += int64(b.Dx()) * int64(b.Dy()) * 4
+	c.stats.Last = f.Received
+	c.stats.Width, c.stats.Height = b.Dx(), b.Dy()
+	return nil
+}
+
+// Stats 返回当前的统计结果
+func (c *Counter) Stats() CounterStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
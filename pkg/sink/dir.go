@@ -0,0 +1,72 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"snap-screen/pkg/client"
+)
+
+// 图片格式
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// Dir 把每一帧按收到的顺序保存为 dir 下的 frame-000001.jpg、frame-000002.jpg……
+type Dir struct {
+	dir     string
+	format  string
+	quality int
+
+	mu sync.Mutex
+	n  int
+}
+
+// NewDir 创建目录（已存在时沿用）；format 为 FormatJPEG 或 FormatPNG，quality 只对 JPEG 有效，零值为默认质量
+func NewDir(dir, format string, quality int) (*Dir, error) {
+	if format != FormatJPEG && format != FormatPNG {
+		return nil, errors.New("不支持的图片格式: " + format)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Dir{dir: dir, format: format, quality: quality}, nil
+}
+
+// WriteFrame 实现 client.FrameSink
+func (d *Dir) WriteFrame(f client.Frame) error {
+	var data []byte
+	ext := ".jpg"
+	if d.format == FormatPNG {
+		var buf bytes.Buffer
+		enc := png.Encoder{CompressionLevel: png.BestSpeed}
+		if err := enc.Encode(&buf, f.Image); err != nil {
+			return err
+		}
+		data, ext = buf.Bytes(), ".png"
+	} else {
+		var err error
+		if data, err = encodeJPEG(f, d.quality); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	d.n++
+	name := fmt.Sprintf("frame-%06d%s", d.n, ext)
+	d.mu.Unlock()
+	return os.WriteFile(filepath.Join(d.dir, name), data, 0o644)
+}
+
+// Count 返回已保存的帧数
+func (d *Dir) Count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.n
+}
@@ -0,0 +1,45 @@
+package sink
+
+import (
+	"io"
+	"sync"
+
+	"snap-screen/pkg/client"
+)
+
+// MJPEG 把每一帧编码为 JPEG 后首尾相接写入 w（不带 multipart 边界），
+// ffmpeg -f mjpeg、source.MJPEGReplay 等都可以直接读取
+type MJPEG struct {
+	quality int
+
+	mu sync.Mutex
+	w  io.Writer
+	n  int
+}
+
+// NewMJPEG 创建 MJPEG 输出；quality 只在需要重新编码时使用，零值为默认质量
+func NewMJPEG(w io.Writer, quality int) *MJPEG {
+	return &MJPEG{w: w, quality: quality}
+}
+
+// WriteFrame 实现 client.FrameSink
+func (m *MJPEG) WriteFrame(f client.Frame) error {
+	data, err := encodeJPEG(f, m.quality)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.w.Write(data); err != nil {
+		return err
+	}
+	m.n++
+	return nil
+}
+
+// Count 返回已写入的帧数
+func (m *MJPEG) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.n
+}
@@ -0,0 +1,315 @@
+package sink
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"snap-screen/pkg/client"
+)
+
+const (
+	// DefaultMJPEGAddr / DefaultMJPEGMaxClients 为本地转发的默认监听地址和客户端上限
+	DefaultMJPEGAddr       = "127.0.0.1:8090"
+	DefaultMJPEGMaxClients = 8
+
+	mjpegBoundary = "snapscreenframe"
+	// 单帧写出超过该时长的客户端视为已断开
+	mjpegWriteTimeout = 10 * time.Second
+)
+
+// errNoFrame 表示还没有收到画面
+var errNoFrame = errors.New("尚未收到画面")
+
+// MJPEGServerOptions 控制本地 MJPEG 转发
+type MJPEGServerOptions struct {
+	// Addr 为监听地址，空时为 127.0.0.1:8090；端口为 0 时由系统分配
+	Addr string
+	// MaxClients 为同时观看 MJPEG 流的客户端上限，超出时返回 503，零值为 8；/snapshot.jpg 不计入
+	MaxClients int
+	// Username / Password 任一不为空时要求 HTTP Basic 认证
+	Username string
+	Password string
+	// Quality 只在需要重新编码 JPEG 时使用，零值为默认质量
+	Quality int
+}
+
+// MJPEGServer 在本地 HTTP 端口上把收到的画面转发为 MJPEG，供 OBS 浏览器源、VLC、网页 <img> 等直接使用：
+//
+//	/stream.mjpg   multipart/x-mixed-replace 的 MJPEG 流
+//	/snapshot.jpg  最新的一帧
+//	/              内嵌 MJPEG 流的网页
+//
+// 编码和发送在后台进行，不拖慢收帧；跟不上的客户端跳过中间的帧。用完后调用 Close
+type MJPEGServer struct {
+	opts MJPEGServerOptions
+	ln   net.Listener
+	srv  *http.Server
+
+	notify   chan struct{}
+	done     chan struct{}
+	loopDone chan struct{}
+
+	mu      sync.Mutex
+	latest  client.Frame
+	jpeg    []byte // latest 的 JPEG，需要时才编码
+	gen     uint64 // 每收到一帧加一，用于判断编码结果是否仍是最新的
+	clients map[chan []byte]struct{}
+	closed  bool
+}
+
+// NewMJPEGServer 开始监听并返回 MJPEGServer
+func NewMJPEGServer(opts MJPEGServerOptions) (*MJPEGServer, error) {
+	if opts.Addr == "" {
+		opts.Addr = DefaultMJPEGAddr
+	}
+	if opts.MaxClients < 0 {
+		return nil, errors.New("客户端上限不能为负数")
+	}
+	if opts.MaxClients == 0 {
+		opts.MaxClients = DefaultMJPEGMaxClients
+	}
+	ln, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &MJPEGServer{
+		opts:     opts,
+		ln:       ln,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		loopDone: make(chan struct{}),
+		clients:  map[chan []byte]struct{}{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream.mjpg", s.auth(s.serveStream))
+	mux.HandleFunc("/snapshot.jpg", s.auth(s.serveSnapshot))
+	mux.HandleFunc("/{$}", s.auth(s.serveIndex))
+	s.srv = &http.Server{Handler: mux}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("mjpeg server error: %v", err)
+		}
+	}()
+	go s.broadcastLoop()
+	return s, nil
+}
+
+// WriteFrame 实现 client.FrameSink；只记录最新一帧，编码和发送在后台进行
+func (s *MJPEGServer) WriteFrame(f client.Frame) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.latest, s.jpeg = f, f.JPEG
+	s.gen++
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// broadcastLoop 把最新一帧发给所有 MJPEG 流客户端，没有客户端时不编码
+func (s *MJPEGServer) broadcastLoop() {
+	defer close(s.loopDone)
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.notify:
+		}
+		if s.Clients() == 0 {
+			continue
+		}
+		data, err := s.current()
+		if err != nil {
+			log.Println("mjpeg server encode frame error:", err)
+			continue
+		}
+		s.mu.Lock()
+		for ch := range s.clients {
+			offerJPEG(ch, data)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// current 返回最新一帧的 JPEG，首次需要时编码并缓存
+func (s *MJPEGServer) current() ([]byte, error) {
+	s.mu.Lock()
+	if s.latest.Image == nil {
+		s.mu.Unlock()
+		return nil, errNoFrame
+	}
+	if s.jpeg != nil {
+		defer s.mu.Unlock()
+		return s.jpeg, nil
+	}
+	f, gen := s.latest, s.gen
+	s.mu.Unlock()
+
+	data, err := encodeJPEG(f, s.opts.Quality)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.gen == gen {
+		s.jpeg = data
+	}
+	s.mu.Unlock()
+	return data, nil
+}
+
+// offerJPEG 把 data 放入容量为 1 的 ch，替换客户端还没取走的旧帧
+func offerJPEG(ch chan []byte, data []byte) {
+	for {
+		select {
+		case ch <- data:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+// auth 在设置了用户名或密码时要求 HTTP Basic 认证
+func (s *MJPEGServer) auth(h http.HandlerFunc) http.HandlerFunc {
+	if s.opts.Username == "" && s.opts.Password == "" {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(s.opts.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(s.opts.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="snap-screen", charset="UTF-8"`)
+			http.Error(w, "需要认证", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *MJPEGServer) serveStream(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan []byte, 1)
+	s.mu.Lock()
+	if len(s.clients) >= s.opts.MaxClients {
+		s.mu.Unlock()
+		http.Error(w, "客户端数量已达上限", http.StatusServiceUnavailable)
+		return
+	}
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	// 已有画面时立即发送，不必等下一帧
+	if data, err := s.current(); err == nil {
+		offerJPEG(ch, data)
+	}
+	rc := http.NewResponseController(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case data := <-ch:
+			_ = rc.SetWriteDeadline(time.Now().Add(mjpegWriteTimeout))
+			if err := writeMJPEGPart(w, data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeMJPEGPart 写出 multipart 中的一帧
+func writeMJPEGPart(w io.Writer, data []byte) error {
+	if _, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", mjpegBoundary, len(data)); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+func (s *MJPEGServer) serveSnapshot(w http.ResponseWriter, r *http.Request) {
+	data, err := s.current()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	w.Write(data)
+}
+
+func (s *MJPEGServer) serveIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>SnapScreen</title>
+<style>html,body{margin:0;height:100%;background:#000}img{width:100%;height:100%;object-fit:contain}</style>
+</head><body><img src="stream.mjpg" alt=""></body></html>
+`)
+}
+
+// Addr 返回实际的监听地址
+func (s *MJPEGServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// URL 返回 MJPEG 流的地址；监听所有网卡时以 127.0.0.1 表示本机
+func (s *MJPEGServer) URL() string {
+	host, port, _ := net.SplitHostPort(s.Addr())
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/stream.mjpg"
+}
+
+// Clients 返回正在观看 MJPEG 流的客户端数
+func (s *MJPEGServer) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// Close 停止监听并断开所有客户端，可重复调用
+func (s *MJPEGServer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.done)
+	err := s.srv.Close()
+	<-s.loopDone
+	return err
+}
@@ -0,0 +1,259 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"snap-screen/pkg/avi"
+	"snap-screen/pkg/client"
+)
+
+const (
+	// DefaultRingMaxAge / DefaultRingMaxBytes 为回看缓冲的默认时长和内存上限
+	DefaultRingMaxAge   = time.Minute
+	DefaultRingMaxBytes = 256 << 20
+
+	// 等待重新编码的帧数上限，编码跟不上时丢弃新帧，不阻塞收帧
+	ringEncodeQueue = 4
+)
+
+// RingOptions 控制回看缓冲，时长和内存两个上限先到者生效
+type RingOptions struct {
+	// MaxAge 为保留的时长，零值为 1 分钟
+	MaxAge time.Duration
+	// MaxBytes 为保留的 JPEG 数据总量上限，零值为 256 MB
+	MaxBytes int64
+	// Quality 只在需要重新编码 JPEG 时使用，零值为默认质量
+	Quality int
+}
+
+// RingFrame 为回看缓冲中的一帧，画面以 JPEG 保存
+type RingFrame struct {
+	JPEG     []byte
+	Seq      uint32
+	Received time.Time
+	Region   client.ZoomRegion
+	Size     image.Point
+}
+
+// Image 解码该帧
+func (f RingFrame) Image() (image.Image, error) {
+	return jpeg.Decode(bytes.NewReader(f.JPEG))
+}
+
+// Ring 在内存中保留最近一段时间收到的帧，用于暂停、回看和保存片段。
+// 以 JPEG 传输的帧原样保存；其他帧在后台 goroutine 中重新编码，不拖慢收帧。用完后调用 Close
+type Ring struct {
+	opts RingOptions
+
+	mu      sync.Mutex
+	frames  []RingFrame // 按 Received 排序，最旧的在前
+	bytes   int64
+	dropped int
+	closed  bool
+
+	encode chan client.Frame
+	done   chan struct{}
+}
+
+// NewRing 创建回看缓冲
+func NewRing(opts RingOptions) *Ring {
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultRingMaxAge
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultRingMaxBytes
+	}
+	r := &Ring{opts: opts, encode: make(chan client.Frame, ringEncodeQueue), done: make(chan struct{})}
+	go r.encodeLoop()
+	return r
+}
+
+// WriteFrame 实现 client.FrameSink；Close 之后收到的帧被忽略
+func (r *Ring) WriteFrame(f client.Frame) error {
+	if f.Received.IsZero() {
+		f.Received = time.Now()
+	}
+	if f.JPEG != nil {
+		r.add(f, f.JPEG)
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	select {
+	case r.encode <- f:
+	default:
+		r.dropped++
+	}
+	return nil
+}
+
+func (r *Ring) encodeLoop() {
+	defer close(r.done)
+	for f := range r.encode {
+		data, err := encodeJPEG(f, r.opts.Quality)
+		if err != nil {
+			continue
+		}
+		r.add(f, data)
+	}
+}
+
+// add 按时间顺序插入一帧并淘汰超出上限的旧帧
+func (r *Ring) add(f client.Frame, data []byte) {
+	rf := RingFrame{JPEG: data, Seq: f.Seq, Received: f.Received, Region: f.Region, Size: f.Image.Bounds().Size()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	i := sort.Search(len(r.frames), func(i int) bool { return r.frames[i].Received.After(rf.Received) })
+	r.frames = append(r.frames, RingFrame{})
+	copy(r.frames[i+1:], r.frames[i:])
+	r.frames[i] = rf
+	r.bytes += int64(len(data))
+
+	newest := r.frames[len(r.frames)-1].Received
+	for len(r.frames) > 1 && (r.bytes > r.opts.MaxBytes || newest.Sub(r.frames[0].Received) > r.opts.MaxAge) {
+		r.bytes -= int64(len(r.frames[0].JPEG))
+		// 清空引用，让被淘汰的数据可以回收
+		r.frames[0] = RingFrame{}
+		r.frames = r.frames[1:]
+	}
+}
+
+// Span 返回缓冲中最旧和最新一帧的时间，缓冲为空时 ok 为 false
+func (r *Ring) Span() (oldest, newest time.Time, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.frames) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	return r.frames[0].Received, r.frames[len(r.frames)-1].Received, true
+}
+
+// At 返回 t 时刻显示的帧，即不晚于 t 的最后一帧；t 早于最旧的帧时返回最旧的帧
+func (r *Ring) At(t time.Time) (RingFrame, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.frames) == 0 {
+		return RingFrame{}, false
+	}
+	i := sort.Search(len(r.frames), func(i int) bool { return r.frames[i].Received.After(t) })
+	return r.frames[max(i-1, 0)], true
+}
+
+// Since 返回不早于 t 的全部帧
+func (r *Ring) Since(t time.Time) []RingFrame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := sort.Search(len(r.frames), func(i int) bool { return !r.frames[i].Received.Before(t) })
+	return append([]RingFrame(nil), r.frames[i:]...)
+}
+
+// Len 返回缓冲中的帧数和 JPEG 数据总量
+func (r *Ring) Len() (frames int, bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.frames), r.bytes
+}
+
+// Dropped 返回重新编码跟不上而没有进入缓冲的帧数
+func (r *Ring) Dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+// WriteAVI 把 since 之后的帧按收到的时间写成 Motion-JPEG AVI，fps 为名义帧率，零值为默认值。
+// AVI 只有一种画面尺寸，与最新一帧尺寸不同的帧被跳过。返回写入的帧数
+func (r *Ring) WriteAVI(ws io.WriteSeeker, since time.Time, fps int) (int, error) {
+	frames := r.Since(since)
+	if len(frames) == 0 {
+		return 0, errors.New("缓冲中没有画面")
+	}
+	if fps <= 0 {
+		fps = DefaultAVIFrameRate
+	}
+	size := frames[len(frames)-1].Size
+	w, err := avi.NewWriter(ws, size.X, size.Y, fps)
+	if err != nil {
+		return 0, err
+	}
+	var start time.Time
+	n := 0
+	for _, f := range frames {
+		if f.Size != size {
+			continue
+		}
+		if start.IsZero() {
+			start = f.Received
+		}
+		ok, err := w.WriteFrame(f.JPEG, f.Received.Sub(start))
+		if errors.Is(err, avi.ErrTooLarge) {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, w.Close()
+}
+
+// SaveClip 把最近 last 时长的画面保存为 dir 下的 <prefix>-clip-20060102-150405.avi，返回文件路径
+func (r *Ring) SaveClip(dir, prefix string, last time.Duration) (string, error) {
+	_, newest, ok := r.Span()
+	if !ok {
+		return "", errors.New("缓冲中没有画面")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-clip-%s.avi", prefix, time.Now().Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	_, err = r.WriteAVI(f, newest.Add(-last), DefaultAVIFrameRate)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// Close 停止后台编码并释放缓冲，可重复调用
+func (r *Ring) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.encode)
+	r.mu.Unlock()
+	<-r.done
+
+	r.mu.Lock()
+	r.frames, r.bytes = nil, 0
+	r.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,27 @@
+// Package sink 提供 client.FrameSink 的几种实现：按序号保存图片、输出 MJPEG 流、在本地 HTTP 端口转发 MJPEG、录制 AVI、回看缓冲、只计数测速
+package sink
+
+import (
+	"bytes"
+	"image/jpeg"
+
+	"snap-screen/pkg/client"
+)
+
+// DefaultJPEGQuality 为需要重新编码 JPEG 时的默认质量
+const DefaultJPEGQuality = 85
+
+// encodeJPEG 返回帧的 JPEG 数据；帧本身以 JPEG 传输时直接使用原始数据，避免二次压缩
+func encodeJPEG(f client.Frame, quality int) ([]byte, error) {
+	if f.JPEG != nil {
+		return f.JPEG, nil
+	}
+	if quality <= 0 || quality > 100 {
+		quality = DefaultJPEGQuality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, f.Image, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,214 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// MJPEGReplay 回放由连续 JPEG 拼接而成的 MJPEG 文件（例如 view 命令输出的 .mjpeg）
+type MJPEGReplay struct {
+	interval time.Duration
+	loop     bool
+
+	mu      sync.Mutex
+	f       *os.File
+	r       *bufio.Reader
+	current *image.RGBA
+	nextAt  time.Time
+	ended   bool
+}
+
+// NewMJPEGReplay 打开 MJPEG 文件，按 fps（非正时为 30）推进；loop 为 true 时播完从头开始
+func NewMJPEGReplay(path string, fps int, loop bool) (*MJPEGReplay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if fps <= 0 {
+		fps = 30
+	}
+	return &MJPEGReplay{
+		interval: time.Second / time.Duration(fps),
+		loop:     loop,
+		f:        f,
+		r:        bufio.NewReaderSize(f, 256*1024),
+	}, nil
+}
+
+// CaptureFrame 返回当前应显示的帧；播放结束且不循环时保持最后一帧
+func (m *MJPEGReplay) CaptureFrame() *image.RGBA {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if !m.ended && (m.current == nil || !now.Before(m.nextAt)) {
+		img, err := m.nextFrame()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Println("mjpeg replay read failed:", err)
+			}
+			m.ended = true
+		} else {
+			m.current = img
+			m.nextAt = now.Add(m.interval)
+		}
+	}
+	return cloneRGBA(m.current)
+}
+
+// Close 关闭底层文件
+func (m *MJPEGReplay) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.f.Close()
+}
+
+func (m *MJPEGReplay) nextFrame() (*image.RGBA, error) {
+	data, err := ReadJPEGFrame(m.r)
+	if errors.Is(err, io.EOF) && m.loop {
+		if _, serr := m.f.Seek(0, io.SeekStart); serr != nil {
+			return nil, serr
+		}
+		m.r.Reset(m.f)
+		data, err = ReadJPEGFrame(m.r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return toRGBA(img), nil
+}
+
+// ReadJPEGFrame 从连续拼接的 JPEG 流中读出一帧完整的 JPEG 数据（SOI 到 EOI）。
+// 按标记段解析而不是简单搜索 FFD9，避免被 EXIF 缩略图等内嵌数据误切。
+func ReadJPEGFrame(r *bufio.Reader) ([]byte, error) {
+	// 跳过帧间可能存在的填充字节，直到 SOI
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != 0xff {
+			continue
+		}
+		next, err := r.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if next == 0xd8 {
+			break
+		}
+		if next == 0xff {
+			_ = r.UnreadByte()
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0xff, 0xd8})
+	var pending byte // 熵编码数据结束处已读出的标记
+	for {
+		marker := pending
+		pending = 0
+		if marker == 0 {
+			m, err := readMarker(r, &buf)
+			if err != nil {
+				return nil, err
+			}
+			marker = m
+		}
+		switch {
+		case marker == 0xd9: // EOI
+			return buf.Bytes(), nil
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			// 无长度字段的独立标记
+			continue
+		}
+		if err := copySegment(r, &buf); err != nil {
+			return nil, err
+		}
+		if marker == 0xda { // SOS 之后是熵编码数据
+			m, err := copyEntropyData(r, &buf)
+			if err != nil {
+				return nil, err
+			}
+			pending = m
+		}
+	}
+}
+
+// readMarker 读取下一个 0xFF xx 标记并写入 buf，返回 xx
+func readMarker(r *bufio.Reader, buf *bytes.Buffer) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if b != 0xff {
+		return 0, errors.New("invalid jpeg marker")
+	}
+	for {
+		m, err := r.ReadByte()
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if m == 0xff {
+			continue
+		}
+		buf.Write([]byte{0xff, m})
+		return m, nil
+	}
+}
+
+// copySegment 复制带长度字段的标记段
+func copySegment(r *bufio.Reader, buf *bytes.Buffer) error {
+	var lenBytes [2]byte
+	if _, err := io.ReadFull(r, lenBytes[:]); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	n := int(lenBytes[0])<<8 | int(lenBytes[1])
+	if n < 2 {
+		return errors.New("invalid jpeg segment length")
+	}
+	buf.Write(lenBytes[:])
+	if _, err := io.CopyN(buf, r, int64(n-2)); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// copyEntropyData 复制熵编码数据，遇到非填充、非 RST 的标记时停止，
+// 该标记同样写入 buf 并作为返回值交给调用方处理
+func copyEntropyData(r *bufio.Reader, buf *bytes.Buffer) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if b != 0xff {
+			buf.WriteByte(b)
+			continue
+		}
+		next, err := r.ReadByte()
+		for err == nil && next == 0xff {
+			next, err = r.ReadByte()
+		}
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		buf.Write([]byte{0xff, next})
+		if next == 0x00 || (next >= 0xd0 && next <= 0xd7) {
+			continue
+		}
+		return next, nil
+	}
+}
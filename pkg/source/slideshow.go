@@ -0,0 +1,92 @@
+package source
+
+import (
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 幻灯片支持的图片扩展名
+var slideExts = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+}
+
+// Slideshow 按文件名顺序循环播放目录中的图片
+type Slideshow struct {
+	files    []string
+	interval time.Duration
+
+	mu      sync.Mutex
+	index   int
+	current *image.RGBA
+	shownAt time.Time
+}
+
+// NewSlideshow 扫描 dir 中的图片，每张展示 interval（非正时为 5 秒）
+func NewSlideshow(dir string, interval time.Duration) (*Slideshow, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || !slideExts[strings.ToLower(filepath.Ext(e.Name()))] {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	if len(files) == 0 {
+		return nil, errors.New("目录中没有可用图片: " + dir)
+	}
+	sort.Strings(files)
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &Slideshow{files: files, interval: interval, index: -1}, nil
+}
+
+// CaptureFrame 返回当前幻灯片，到时间后切换到下一张；无法解码的图片会被跳过
+func (s *Slideshow) CaptureFrame() *image.RGBA {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil || time.Since(s.shownAt) >= s.interval {
+		for range s.files {
+			s.index = (s.index + 1) % len(s.files)
+			img, err := loadImage(s.files[s.index])
+			if err != nil {
+				log.Println("slideshow load image failed:", err)
+				continue
+			}
+			s.current = img
+			s.shownAt = time.Now()
+			break
+		}
+	}
+	return cloneRGBA(s.current)
+}
+
+func loadImage(path string) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return toRGBA(img), nil
+}
@@ -0,0 +1,38 @@
+// Package source 定义 Publisher 的帧来源，以及不依赖物理屏幕的几种实现
+package source
+
+import (
+	"image"
+	"image/draw"
+
+	"snap-screen/pkg/screen"
+)
+
+// FrameSource 是 Publisher 的帧来源。
+// CaptureFrame 每次都应返回一张新的图像（调用方会就地绘制水印等），暂时没有可用帧时返回 nil。
+type FrameSource interface {
+	CaptureFrame() *image.RGBA
+}
+
+// *screen.Capture 是默认的帧来源
+var _ FrameSource = (*screen.Capture)(nil)
+
+// cloneRGBA 复制一张图像，供缓存帧的来源每次返回独立副本
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	if src == nil {
+		return nil
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
+
+// toRGBA 把任意图像转换为从 (0,0) 开始的 RGBA
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
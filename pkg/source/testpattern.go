@@ -0,0 +1,95 @@
+package source
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+	"time"
+
+	"snap-screen/pkg/overlay"
+)
+
+// 经典彩条配色
+var patternBars = []color.RGBA{
+	{0xc0, 0xc0, 0xc0, 0xff},
+	{0xc0, 0xc0, 0x00, 0xff},
+	{0x00, 0xc0, 0xc0, 0xff},
+	{0x00, 0xc0, 0x00, 0xff},
+	{0xc0, 0x00, 0xc0, 0xff},
+	{0xc0, 0x00, 0x00, 0xff},
+	{0x00, 0x00, 0xc0, 0xff},
+}
+
+// TestPattern 生成带帧计数的动态测试图案，适合无显示器的 CI / 压测 / 演示
+type TestPattern struct {
+	width, height int
+	start         time.Time
+	text          *overlay.Renderer
+
+	// mu 保护帧计数和 text：overlay.Renderer 只能在一个 goroutine 中使用
+	mu    sync.Mutex
+	frame uint64
+}
+
+// NewTestPattern 创建指定分辨率的测试图案来源，宽高非正时使用 1280x720
+func NewTestPattern(width, height int) *TestPattern {
+	if width <= 0 || height <= 0 {
+		width, height = 1280, 720
+	}
+	// 渲染器只使用内置字体，解析失败时退化为不绘制文字
+	text, _ := overlay.NewRenderer(overlay.Config{})
+	return &TestPattern{
+		width:  width,
+		height: height,
+		start:  time.Now(),
+		text:   text,
+	}
+}
+
+// CaptureFrame 生成下一帧：彩条整体横向滚动，一个方块在画面中弹跳，中央显示帧号
+func (p *TestPattern) CaptureFrame() *image.RGBA {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frame++
+	n := p.frame
+
+	w, h := p.width, p.height
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	barW := max(w/len(patternBars), 1)
+	shift := int(n*4) % w
+	for i := 0; i*barW < w+barW; i++ {
+		c := patternBars[i%len(patternBars)]
+		x := i*barW - shift
+		draw.Draw(img, image.Rect(x, 0, x+barW, h), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+
+	// 弹跳方块，便于肉眼判断卡顿和撕裂
+	box := max(h/8, 4)
+	bx := bounce(int(n*7), w-box)
+	by := bounce(int(n*5), h-box)
+	draw.Draw(img, image.Rect(bx, by, bx+box, by+box), image.White, image.Point{}, draw.Src)
+
+	label := fmt.Sprintf("#%06d  %s", n, time.Since(p.start).Truncate(time.Millisecond))
+	p.text.DrawText(img, overlay.Element{
+		Anchor:     overlay.Center,
+		Size:       0.08,
+		Background: color.RGBA{A: 0xb0},
+	}, label)
+	return img
+}
+
+// bounce 把单调递增的位移映射为 [0, limit] 内来回运动的坐标
+func bounce(pos, limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+	period := 2 * limit
+	pos %= period
+	if pos > limit {
+		return period - pos
+	}
+	return pos
+}
@@ -0,0 +1,306 @@
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// incoming 为一次接收。file 在 Accept 之前为 nil
+type incoming struct {
+	offer Offer
+
+	mu         sync.Mutex
+	dir        string
+	partPath   string
+	file       *os.File
+	state      State
+	received   int64
+	resumed    int64
+	path       string
+	lastReport time.Time
+}
+
+func (in *incoming) progress(err error) Progress {
+	return Progress{
+		ID:          in.offer.ID,
+		Name:        in.offer.Name,
+		Direction:   Receiving,
+		State:       in.state,
+		Size:        in.offer.Size,
+		Transferred: in.received,
+		Resumed:     in.resumed,
+		Path:        in.path,
+		Err:         err,
+	}
+}
+
+// handleOffer 校验对方的 offer 并交给上层决定是否接受
+func (m *Manager) handleOffer(msg message) {
+	if msg.Offer == nil {
+		return
+	}
+	offer := *msg.Offer
+	offer.ID = msg.ID
+	reason := ""
+	name, err := sanitizeName(offer.Name)
+	switch {
+	case err != nil:
+		reason = err.Error()
+	case offer.Size < 0:
+		reason = "文件大小无效"
+	case !validSHA256(offer.SHA256):
+		reason = "SHA-256 格式无效"
+	case m.cfg.OnOffer == nil:
+		reason = "对方未开启文件接收"
+	}
+	if reason != "" {
+		_ = m.sendMessage(message{Type: msgReject, ID: offer.ID, Error: reason})
+		return
+	}
+	offer.Name = name
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.receives[offer.ID] = &incoming{offer: offer, state: StateWaiting}
+	m.mu.Unlock()
+	m.cfg.OnOffer(offer)
+}
+
+// Accept 接受对方的文件，保存到 dir 目录。dir 中已有同一文件的未完成部分时从断点续传。
+func (m *Manager) Accept(id, dir string) error {
+	in := m.incoming(id)
+	if in == nil {
+		return errors.New("传输不存在或已结束: " + id)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// 未完成部分以内容哈希命名，同名但内容不同的文件不会误续传
+	part := filepath.Join(dir, "."+in.offer.Name+"."+in.offer.SHA256[:12]+".part")
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	offset := min(st.Size(), in.offer.Size)
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return err
+	}
+
+	in.mu.Lock()
+	if in.state != StateWaiting {
+		in.mu.Unlock()
+		f.Close()
+		return errors.New("传输已开始或已结束: " + id)
+	}
+	in.dir, in.partPath, in.file = dir, part, f
+	in.state = StateActive
+	in.received, in.resumed = offset, offset
+	p := in.progress(nil)
+	in.mu.Unlock()
+
+	m.report(p)
+	if err := m.sendMessage(message{Type: msgAccept, ID: id, Offset: offset}); err != nil {
+		m.finishReceive(id, StateFailed, err)
+		return err
+	}
+	return nil
+}
+
+// Reject 拒绝对方的文件
+func (m *Manager) Reject(id, reason string) error {
+	m.finishReceive(id, StateRejected, nil)
+	if reason == "" {
+		reason = "对方拒绝接收"
+	}
+	return m.sendMessage(message{Type: msgReject, ID: id, Error: reason})
+}
+
+func (m *Manager) incoming(id string) *incoming {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.receives[id]
+}
+
+// handleChunk 按顺序写入数据块；DataChannel 可靠有序，偏移不连续说明双方状态已不一致
+func (m *Manager) handleChunk(buf []byte) {
+	id, offset, data, err := decodeChunk(buf)
+	if err != nil {
+		log.Println("transfer decode chunk error:", err)
+		return
+	}
+	in := m.incoming(id)
+	if in == nil {
+		return
+	}
+	in.mu.Lock()
+	if in.state != StateActive {
+		in.mu.Unlock()
+		return
+	}
+	switch {
+	case offset != in.received:
+		err = fmt.Errorf("数据块偏移不连续: 期望 %d，收到 %d", in.received, offset)
+	case in.received+int64(len(data)) > in.offer.Size:
+		err = errors.New("收到的数据超过文件大小")
+	default:
+		_, err = in.file.WriteAt(data, offset)
+	}
+	if err != nil {
+		in.mu.Unlock()
+		m.failReceive(id, err)
+		return
+	}
+	in.received += int64(len(data))
+	var p *Progress
+	if time.Since(in.lastReport) >= 100*time.Millisecond {
+		in.lastReport = time.Now()
+		pp := in.progress(nil)
+		p = &pp
+	}
+	in.mu.Unlock()
+	if p != nil {
+		m.report(*p)
+	}
+}
+
+// handleDone 在发送方发完全部数据后校验 SHA-256，通过后改为正式文件名
+func (m *Manager) handleDone(msg message) {
+	in := m.incoming(msg.ID)
+	if in == nil {
+		return
+	}
+	in.mu.Lock()
+	if in.state != StateActive {
+		in.mu.Unlock()
+		return
+	}
+	if in.received != in.offer.Size {
+		in.mu.Unlock()
+		m.failReceive(msg.ID, fmt.Errorf("数据不完整: %d / %d 字节", in.received, in.offer.Size))
+		return
+	}
+	in.state = StateVerifying
+	f := in.file
+	in.file = nil
+	p := in.progress(nil)
+	in.mu.Unlock()
+	m.report(p)
+
+	// 大文件的校验较慢，放到单独的 goroutine，避免阻塞通道上的其他消息
+	go func() {
+		sum, err := hashFile(f)
+		f.Close()
+		if err == nil && sum != in.offer.SHA256 {
+			// 内容已损坏，续传也无意义，删除未完成部分
+			os.Remove(in.partPath)
+			err = errors.New("SHA-256 校验失败")
+		}
+		var final string
+		if err == nil {
+			final = uniquePath(in.dir, in.offer.Name)
+			err = os.Rename(in.partPath, final)
+		}
+		if err != nil {
+			m.failReceive(msg.ID, err)
+			return
+		}
+		in.mu.Lock()
+		in.path = final
+		in.mu.Unlock()
+		m.finishReceive(msg.ID, StateCompleted, nil)
+		_ = m.sendMessage(message{Type: msgComplete, ID: msg.ID})
+	}()
+}
+
+// failReceive 以失败结束接收并通知对方
+func (m *Manager) failReceive(id string, err error) {
+	if m.finishReceive(id, StateFailed, err) {
+		log.Println("transfer receive", id, "failed:", err)
+		_ = m.sendMessage(message{Type: msgComplete, ID: id, Error: err.Error()})
+	}
+}
+
+// finishReceive 结束一次接收。未完成部分保留在磁盘上，之后重新发送同一文件时可续传。
+// 重复调用或 ID 不存在时返回 false。
+func (m *Manager) finishReceive(id string, state State, err error) bool {
+	m.mu.Lock()
+	in, ok := m.receives[id]
+	if ok {
+		delete(m.receives, id)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+	in.mu.Lock()
+	if in.file != nil {
+		in.file.Close()
+		in.file = nil
+	}
+	in.state = state
+	if state == StateCompleted {
+		in.received = in.offer.Size
+	}
+	p := in.progress(err)
+	in.mu.Unlock()
+	m.report(p)
+	return true
+}
+
+func hashFile(f *os.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, 1<<62)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sanitizeName 只保留文件名部分，防止对方通过 ../ 等写到目标目录之外
+func sanitizeName(name string) (string, error) {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	switch name {
+	case "", ".", "..", "/":
+		return "", errors.New("文件名无效")
+	}
+	if strings.HasPrefix(name, ".") {
+		// 避免与 .part 文件混淆，也避免收到隐藏文件
+		name = "_" + name
+	}
+	return name, nil
+}
+
+func validSHA256(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == sha256.Size
+}
+
+// uniquePath 返回 dir 下不与已有文件冲突的路径，冲突时追加 " (1)"、" (2)" 等
+func uniquePath(dir, name string) string {
+	path := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
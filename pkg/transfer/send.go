@@ -0,0 +1,221 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// outgoing 为一次发送
+type outgoing struct {
+	offer  Offer
+	src    io.ReaderAt
+	closer io.Closer // 发送文件时为 *os.File，结束后关闭
+	path   string
+
+	state   State
+	sent    int64
+	resumed int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// SendFile 计算文件的 SHA-256 后向对方发出 offer，对方接受后在后台发送，返回传输 ID
+func (m *Manager) SendFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return "", err
+	}
+	if st.IsDir() {
+		f.Close()
+		return "", errors.New("不支持发送文件夹: " + path)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		f.Close()
+		return "", err
+	}
+	offer := Offer{Name: filepath.Base(path), Size: st.Size(), SHA256: hex.EncodeToString(h.Sum(nil))}
+	id, err := m.send(offer, f, f, path)
+	if err != nil {
+		f.Close()
+	}
+	return id, err
+}
+
+// SendBytes 把内存中的数据作为名为 name 的文件发送，用于截图等不落盘的内容；
+// kind 原样出现在对方收到的 Offer 中
+func (m *Manager) SendBytes(name, kind string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	offer := Offer{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:]), Kind: kind}
+	return m.send(offer, bytes.NewReader(data), nil, "")
+}
+
+func (m *Manager) send(offer Offer, src io.ReaderAt, closer io.Closer, path string) (string, error) {
+	offer.ID = newID()
+	o := &outgoing{
+		offer:  offer,
+		src:    src,
+		closer: closer,
+		path:   path,
+		state:  StateWaiting,
+		stop:   make(chan struct{}),
+	}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return "", ErrClosed
+	}
+	m.sends[o.offer.ID] = o
+	m.mu.Unlock()
+
+	m.report(o.progress(nil))
+	if err := m.sendMessage(message{Type: msgOffer, ID: offer.ID, Offer: &offer}); err != nil {
+		m.mu.Lock()
+		delete(m.sends, offer.ID)
+		m.mu.Unlock()
+		return "", err
+	}
+	return offer.ID, nil
+}
+
+func (o *outgoing) progress(err error) Progress {
+	return Progress{
+		ID:          o.offer.ID,
+		Name:        o.offer.Name,
+		Direction:   Sending,
+		State:       o.state,
+		Size:        o.offer.Size,
+		Transferred: o.sent,
+		Resumed:     o.resumed,
+		Path:        o.path,
+		Err:         err,
+	}
+}
+
+// handleAccept 在对方接受后从其给出的偏移开始发送
+func (m *Manager) handleAccept(msg message) {
+	m.mu.Lock()
+	o, ok := m.sends[msg.ID]
+	if !ok || o.state != StateWaiting {
+		m.mu.Unlock()
+		return
+	}
+	if msg.Offset < 0 || msg.Offset > o.offer.Size {
+		m.mu.Unlock()
+		m.failSend(msg.ID, errors.New("对方给出的续传位置无效"))
+		return
+	}
+	o.state = StateActive
+	o.sent, o.resumed = msg.Offset, msg.Offset
+	p := o.progress(nil)
+	m.mu.Unlock()
+
+	m.report(p)
+	go m.pump(o)
+}
+
+// pump 分块发送数据，发送缓冲超过上限时等待排空，避免占满内存并拖慢同一连接上的画面
+func (m *Manager) pump(o *outgoing) {
+	buf := make([]byte, m.cfg.ChunkSize)
+	off := o.sent
+	last := time.Now()
+	for off < o.offer.Size {
+		if !m.waitBuffered(o) {
+			return
+		}
+		n := int(min(int64(len(buf)), o.offer.Size-off))
+		n, err := o.src.ReadAt(buf[:n], off)
+		if n == 0 && err != nil {
+			m.failSend(o.offer.ID, err)
+			return
+		}
+		if err := m.ch.Send(encodeChunk(o.offer.ID, off, buf[:n])); err != nil {
+			m.failSend(o.offer.ID, err)
+			return
+		}
+		off += int64(n)
+
+		m.mu.Lock()
+		o.sent = off
+		p := o.progress(nil)
+		m.mu.Unlock()
+		if time.Since(last) >= 100*time.Millisecond {
+			last = time.Now()
+			m.report(p)
+		}
+	}
+
+	m.mu.Lock()
+	o.state = StateVerifying
+	p := o.progress(nil)
+	m.mu.Unlock()
+	m.report(p)
+	if err := m.sendMessage(message{Type: msgDone, ID: o.offer.ID}); err != nil {
+		m.failSend(o.offer.ID, err)
+	}
+}
+
+// waitBuffered 等待发送缓冲降到上限以下，传输被取消时返回 false
+func (m *Manager) waitBuffered(o *outgoing) bool {
+	for m.ch.BufferedAmount() > m.cfg.MaxBuffered {
+		select {
+		case <-o.stop:
+			return false
+		case <-m.drained:
+		case <-time.After(50 * time.Millisecond):
+			// 多个发送共用一个排空通知，定时复查避免漏掉唤醒
+		}
+	}
+	select {
+	case <-o.stop:
+		return false
+	default:
+		return true
+	}
+}
+
+// failSend 以失败结束发送并通知对方
+func (m *Manager) failSend(id string, err error) {
+	if m.finishSend(id, StateFailed, err) {
+		log.Println("transfer send", id, "failed:", err)
+		_ = m.sendMessage(message{Type: msgCancel, ID: id, Error: err.Error()})
+	}
+}
+
+// finishSend 结束一次发送，重复调用或 ID 不存在时返回 false
+func (m *Manager) finishSend(id string, state State, err error) bool {
+	m.mu.Lock()
+	o, ok := m.sends[id]
+	if !ok {
+		m.mu.Unlock()
+		return false
+	}
+	delete(m.sends, id)
+	o.state = state
+	if state == StateCompleted {
+		o.sent = o.offer.Size
+	}
+	p := o.progress(err)
+	m.mu.Unlock()
+
+	o.stopOnce.Do(func() { close(o.stop) })
+	if o.closer != nil {
+		o.closer.Close()
+	}
+	m.report(p)
+	return true
+}
@@ -0,0 +1,269 @@
+// Package transfer 在一条可靠有序的 DataChannel 上双向传输文件：
+// 分块发送、按 BufferedAmount 做流控、断点续传，接收完成后校验 SHA-256。
+//
+// 协议：控制消息为 JSON 文本（offer / accept / reject / done / complete / cancel），
+// 数据块为二进制消息：1 字节 ID 长度 | ID | 8 字节偏移（大端）| 数据。
+// 接收方把未完成的数据写入目标目录下以内容哈希命名的 .part 文件，
+// 之后再次收到同一文件（大小与 SHA-256 相同）的 offer 时从已有长度续传。
+package transfer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+
+	"snap-screen/pkg/utils"
+)
+
+// Channel 为传输使用的数据通道，*webrtc.DataChannel 满足该接口
+type Channel interface {
+	Send(data []byte) error
+	SendText(s string) error
+	BufferedAmount() uint64
+	SetBufferedAmountLowThreshold(th uint64)
+	OnBufferedAmountLow(f func())
+}
+
+// Direction 为传输方向
+type Direction string
+
+const (
+	Sending   Direction = "send"
+	Receiving Direction = "receive"
+)
+
+// State 为传输状态
+type State string
+
+const (
+	StateWaiting   State = "waiting"   // 已发出 offer，等待对方接受
+	StateActive    State = "active"    // 传输中
+	StateVerifying State = "verifying" // 数据已收完，正在校验
+	StateCompleted State = "completed" // 校验通过
+	StateRejected  State = "rejected"  // 对方拒绝
+	StateCanceled  State = "canceled"  // 任一方取消或连接断开
+	StateFailed    State = "failed"    // 读写或校验失败
+)
+
+// Finished 返回是否为终止状态
+func (s State) Finished() bool {
+	switch s {
+	case StateCompleted, StateRejected, StateCanceled, StateFailed:
+		return true
+	}
+	return false
+}
+
+// Offer 描述对方想发送的文件
+type Offer struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Kind 为上层定义的用途标记（如截图），普通文件为空
+	Kind string `json:"kind,omitempty"`
+}
+
+// Progress 为一次传输的进度快照
+type Progress struct {
+	ID        string
+	Name      string
+	Direction Direction
+	State     State
+	Size      int64
+	// Transferred 为已传输（含续传前已有部分）的字节数
+	Transferred int64
+	// Resumed 为续传时跳过的字节数
+	Resumed int64
+	// Path 为发送的源文件或接收完成后的保存路径
+	Path string
+	Err  error
+}
+
+// Config 配置 Manager 的回调与参数
+type Config struct {
+	// OnOffer 在对方发来文件时回调，之后应调用 Accept 或 Reject
+	OnOffer func(Offer)
+	// OnProgress 在传输状态变化及传输过程中（约每 100ms）回调
+	OnProgress func(Progress)
+	// ChunkSize 为单个数据块的大小，零值为 32 KB
+	ChunkSize int
+	// MaxBuffered 为发送缓冲的上限，超过后等待缓冲排空，零值为 4 MB
+	MaxBuffered uint64
+}
+
+const (
+	defaultChunkSize   = 32 << 10
+	defaultMaxBuffered = 4 << 20
+)
+
+// 控制消息类型
+const (
+	msgOffer    = "offer"
+	msgAccept   = "accept"
+	msgReject   = "reject"
+	msgDone     = "done"
+	msgComplete = "complete"
+	msgCancel   = "cancel"
+)
+
+// message 为控制消息，按 Type 使用其中部分字段
+type message struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Offer  *Offer `json:"offer,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ErrClosed 表示 Manager 已关闭
+var ErrClosed = errors.New("传输通道已关闭")
+
+// Manager 管理一条通道上所有进行中的收发，并发安全
+type Manager struct {
+	ch  Channel
+	cfg Config
+
+	mu       sync.Mutex
+	sends    map[string]*outgoing
+	receives map[string]*incoming
+	closed   bool
+
+	// drained 在发送缓冲降到阈值以下时收到通知
+	drained chan struct{}
+}
+
+// NewManager 创建 Manager，调用方需把通道上收到的消息交给 HandleMessage
+func NewManager(ch Channel, cfg Config) *Manager {
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = defaultChunkSize
+	}
+	if cfg.MaxBuffered == 0 {
+		cfg.MaxBuffered = defaultMaxBuffered
+	}
+	m := &Manager{
+		ch:       ch,
+		cfg:      cfg,
+		sends:    make(map[string]*outgoing),
+		receives: make(map[string]*incoming),
+		drained:  make(chan struct{}, 1),
+	}
+	ch.SetBufferedAmountLowThreshold(cfg.MaxBuffered / 2)
+	ch.OnBufferedAmountLow(func() {
+		select {
+		case m.drained <- struct{}{}:
+		default:
+		}
+	})
+	return m
+}
+
+// HandleMessage 处理通道上收到的一条消息
+func (m *Manager) HandleMessage(isString bool, data []byte) {
+	if !isString {
+		m.handleChunk(data)
+		return
+	}
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Println("transfer parse message error:", err)
+		return
+	}
+	switch msg.Type {
+	case msgOffer:
+		m.handleOffer(msg)
+	case msgAccept:
+		m.handleAccept(msg)
+	case msgReject:
+		m.finishSend(msg.ID, StateRejected, errorFrom(msg.Error))
+	case msgDone:
+		m.handleDone(msg)
+	case msgComplete:
+		if msg.Error != "" {
+			m.finishSend(msg.ID, StateFailed, errors.New(msg.Error))
+		} else {
+			m.finishSend(msg.ID, StateCompleted, nil)
+		}
+	case msgCancel:
+		m.finishSend(msg.ID, StateCanceled, errorFrom(msg.Error))
+		m.finishReceive(msg.ID, StateCanceled, errorFrom(msg.Error))
+	}
+}
+
+// Cancel 取消一次收发并通知对方
+func (m *Manager) Cancel(id string) error {
+	m.finishSend(id, StateCanceled, nil)
+	m.finishReceive(id, StateCanceled, nil)
+	return m.sendMessage(message{Type: msgCancel, ID: id})
+}
+
+// Close 取消所有进行中的收发（不通知对方），通道断开时调用
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	sends := make([]string, 0, len(m.sends))
+	for id := range m.sends {
+		sends = append(sends, id)
+	}
+	receives := make([]string, 0, len(m.receives))
+	for id := range m.receives {
+		receives = append(receives, id)
+	}
+	m.mu.Unlock()
+	for _, id := range sends {
+		m.finishSend(id, StateCanceled, ErrClosed)
+	}
+	for _, id := range receives {
+		m.finishReceive(id, StateCanceled, ErrClosed)
+	}
+}
+
+func (m *Manager) sendMessage(msg message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return m.ch.SendText(string(b))
+}
+
+func (m *Manager) report(p Progress) {
+	if m.cfg.OnProgress != nil {
+		m.cfg.OnProgress(p)
+	}
+}
+
+func newID() string {
+	return utils.GenID()
+}
+
+// encodeChunk / decodeChunk 处理二进制数据块的头部
+func encodeChunk(id string, offset int64, data []byte) []byte {
+	buf := make([]byte, 1+len(id)+8+len(data))
+	buf[0] = byte(len(id))
+	copy(buf[1:], id)
+	binary.BigEndian.PutUint64(buf[1+len(id):], uint64(offset))
+	copy(buf[1+len(id)+8:], data)
+	return buf
+}
+
+func decodeChunk(buf []byte) (id string, offset int64, data []byte, err error) {
+	if len(buf) < 1 {
+		return "", 0, nil, errors.New("数据块过短")
+	}
+	n := int(buf[0])
+	if len(buf) < 1+n+8 {
+		return "", 0, nil, errors.New("数据块过短")
+	}
+	id = string(buf[1 : 1+n])
+	offset = int64(binary.BigEndian.Uint64(buf[1+n:]))
+	return id, offset, buf[1+n+8:], nil
+}
+
+func errorFrom(s string) error {
+	if s == "" {
+		return nil
+	}
+	return errors.New(s)
+}